@@ -0,0 +1,355 @@
+package main
+
+import (
+	"weather/internal/clientip"
+	"weather/internal/data"
+	"weather/internal/drawing"
+	"weather/internal/location"
+	"weather/internal/repository"
+	"weather/internal/validation"
+	"weather/internal/weather"
+
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed api/openapi.yaml
+var openAPIDocument []byte
+
+const (
+	apiDefaultPageSize = 20
+	apiMaxPageSize     = 100
+)
+
+// apiError is the body of every unsuccessful API response, matching
+// validation.WriteJSON, which adds problems for 422s:
+//
+//	{"error": {"code": "not_found", "message": "..."}}
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("error encoding API response: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code string, message string) {
+	writeAPIJSON(w, status, struct {
+		Error apiError `json:"error"`
+	}{
+		Error: apiError{Code: code, Message: message},
+	})
+}
+
+// writeAPIFailure responds to err from a handler's dependencies: 422 for
+// validation problems, 502 when an upstream responded with something
+// invalid, nothing when the client went away and 500 otherwise.
+func writeAPIFailure(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, validation.ErrValidation):
+		validation.WriteJSON(w, err, "request")
+	case isUpstreamInvalid(err):
+		log.Printf("error %s: %v", action, err)
+		writeAPIError(w, http.StatusBadGateway, "upstream", "an upstream service responded with something unusable")
+	case errors.Is(err, context.Canceled):
+	default:
+		log.Printf("error %s: %v", action, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal", "something went wrong")
+	}
+}
+
+// readAPIObservationID reads the observation ID path value, responding with
+// a 404 when it can't be one.
+func readAPIObservationID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		writeAPIError(w, http.StatusNotFound, "not_found", "no observation with that ID")
+		return 0, false
+	}
+
+	return id, true
+}
+
+// observationsPage is a page of observations, newest first. Passing
+// NextBefore as before fetches the next page.
+type observationsPage struct {
+	Observations []data.ListObservationsRow `json:"observations"`
+	NextBefore   *int64                     `json:"next_before,omitempty"`
+}
+
+// readListObservationsParams reads the filters for listing observations from
+// query, asking for one more row than the page size to tell whether there
+// is another page.
+func readListObservationsParams(query url.Values) (data.ListObservationsParams, int, error) {
+	v := validation.Validator{}
+	params := data.ListObservationsParams{}
+
+	readInt := func(field string, min int64, max int64) sql.NullInt64 {
+		raw := strings.TrimSpace(query.Get(field))
+		if raw == "" {
+			return sql.NullInt64{}
+		}
+
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			v.Problem(field, "must be a whole number")
+			return sql.NullInt64{}
+		}
+
+		return sql.NullInt64{Int64: n, Valid: validation.Field(&v, field, n, validation.Between(min, max))}
+	}
+
+	pageSize := int64(apiDefaultPageSize)
+	if limit := readInt("limit", 1, apiMaxPageSize); limit.Valid {
+		pageSize = limit.Int64
+	}
+
+	shared := readObservationFilters(&v, query, time.RFC3339)
+	params.BeforeID = shared.BeforeID
+	params.MinTempC = shared.MinTempC
+	params.MaxTempC = shared.MaxTempC
+	params.Since = shared.Since
+	params.Until = shared.Until
+	params.Limit = pageSize + 1
+
+	if raw := strings.TrimSpace(query.Get("weather_code")); raw != "" {
+		code, err := weather.ParseCode(raw)
+		if err != nil {
+			v.Problem("weather_code", "must be a WMO weather code")
+		}
+		params.WeatherCode = sql.NullString{String: code.String(), Valid: true}
+	}
+
+	if raw := strings.TrimSpace(query.Get("has_drawing")); raw != "" {
+		hasDrawing, err := strconv.ParseBool(raw)
+		if err != nil {
+			v.Problem("has_drawing", "must be true or false")
+		}
+		params.HasDrawing = sql.NullBool{Bool: hasDrawing, Valid: true}
+	}
+
+	return params, int(pageSize), v.Err()
+}
+
+func handleAPIObservationsGet(observations repository.ObservationRepository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params, pageSize, err := readListObservationsParams(r.URL.Query())
+		if err != nil {
+			validation.WriteJSON(w, err, "query")
+			return
+		}
+
+		rows, err := observations.List(r.Context(), params)
+		if err != nil {
+			writeAPIFailure(w, err, "listing observations")
+			return
+		}
+
+		page := observationsPage{Observations: rows}
+		if page.Observations == nil {
+			page.Observations = []data.ListObservationsRow{}
+		}
+		if len(rows) > pageSize {
+			page.Observations = rows[:pageSize]
+			next := rows[pageSize-1].Observation.ID
+			page.NextBefore = &next
+		}
+
+		writeAPIJSON(w, http.StatusOK, page)
+	})
+}
+
+func handleAPIObservationGet(observations repository.ObservationRepository, drawings repository.DrawingRepository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := readAPIObservationID(w, r)
+		if !ok {
+			return
+		}
+
+		obs, err := resolveObservationByID(r.Context(), id, observations, drawings)
+		if errors.Is(err, repository.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "not_found", "no observation with that ID")
+			return
+		}
+		if err != nil {
+			writeAPIFailure(w, err, "resolving observation")
+			return
+		}
+
+		writeAPIJSON(w, http.StatusOK, obs)
+	})
+}
+
+// handleAPICurrentObservationGet returns the current observation for the
+// lat and lon, or city, in the query string. Only places visitors have
+// observed are served, so the API can't be used to make upstream requests
+// and store observations for anywhere at all.
+func handleAPICurrentObservationGet(observations repository.ObservationRepository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		override, err := readLocationOverride(r.URL.Query())
+		if err != nil {
+			validation.WriteJSON(w, err, "query")
+			return
+		}
+
+		obs, err := observations.ForObservedLocation(r.Context(), override.geolocation())
+		if errors.Is(err, repository.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "not_found", "nobody has observed the weather there yet")
+			return
+		}
+		if err != nil {
+			writeAPIFailure(w, err, "resolving current observation")
+			return
+		}
+
+		writeAPIJSON(w, http.StatusOK, observationTemplateData{Observation: obs})
+	})
+}
+
+// handleAPIGeolocationGet returns where the client's IP address is.
+func handleAPIGeolocationGet(clientIPs *clientip.Resolver, geolocations repository.GeolocationRepository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, err := clientIPs.ClientIP(r)
+		if err != nil {
+			writeAPIFailure(w, err, "resolving client IP")
+			return
+		}
+
+		loc, err := geolocations.ForIP(r.Context(), addr.String())
+		if errors.Is(err, location.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "not_found", "no location found for your IP address")
+			return
+		}
+		if err != nil {
+			writeAPIFailure(w, err, "resolving geolocation")
+			return
+		}
+
+		writeAPIJSON(w, http.StatusOK, loc)
+	})
+}
+
+// handleAPIObservationDrawingPost stores a drawing for an observation. The
+// body is {"data": "..."}, the drawing base64 encoded as the
+// observation-canvas component submits it.
+func handleAPIObservationDrawingPost(drawings repository.DrawingRepository, limits drawing.Limits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, ok := readAPIObservationID(w, r)
+		if !ok {
+			return
+		}
+
+		body := struct {
+			Data string `json:"data"`
+		}{}
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(base64.StdEncoding.EncodedLen(limits.MaxBytes))+1024))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&body); err != nil {
+			validation.WriteJSON(w, validation.Problem("body", "must be a JSON object like {\"data\": \"...\"}"), "body")
+			return
+		}
+
+		v := validation.Validator{}
+		if validation.Field(&v, "data", body.Data, validation.Required) {
+			// Package drawing reports problems under the form's field name.
+			if _, err := limits.Parse(body.Data); err != nil {
+				for _, problem := range validation.ProblemsOf(err, "data") {
+					v.Problem("data", "%s", problem)
+				}
+			}
+		}
+		if err := v.Err(); err != nil {
+			validation.WriteJSON(w, err, "data")
+			return
+		}
+
+		stored := data.ObservationDrawing{
+			ObservationID: id,
+			Data:          body.Data,
+			SizeBytes:     int64(len(body.Data)),
+			TimeSubmitted: time.Now().UTC(),
+		}
+		switch err := drawings.Add(ctx, stored); {
+		case err == nil:
+			break
+		case errors.Is(err, data.ErrNotFound):
+			writeAPIError(w, http.StatusNotFound, "not_found", "no observation with that ID")
+			return
+		case errors.Is(err, data.ErrConflict):
+			writeAPIError(w, http.StatusConflict, "conflict", "that observation already has a drawing")
+			return
+		case errors.Is(err, data.ErrConstraint):
+			writeAPIError(w, http.StatusBadRequest, "invalid", "that drawing can't be stored")
+			return
+		default:
+			writeAPIFailure(w, err, "storing drawing")
+			return
+		}
+
+		w.Header().Set("Location", "/api/v1/observations/"+strconv.FormatInt(id, 10)+"/drawing")
+		writeAPIJSON(w, http.StatusCreated, stored)
+	})
+}
+
+// handleAPIObservationDrawingGet returns a drawing in its binary format,
+// described in internal/drawing/drawing.go.
+func handleAPIObservationDrawingGet(drawings repository.DrawingRepository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := readAPIObservationID(w, r)
+		if !ok {
+			return
+		}
+
+		stored, err := drawings.ForObservation(r.Context(), id)
+		if errors.Is(err, repository.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "not_found", "that observation has no drawing")
+			return
+		}
+		if err != nil {
+			writeAPIFailure(w, err, "reading drawing")
+			return
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(stored.Data)
+		if err != nil {
+			writeAPIFailure(w, err, "decoding drawing")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Write(raw)
+	})
+}
+
+func handleAPIOpenAPIGet() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPIDocument)
+	})
+}
+
+// handleAPINotFound keeps unknown API paths in the API's error envelope
+// rather than falling through to the HTML pages.
+func handleAPINotFound() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+}
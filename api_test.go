@@ -0,0 +1,188 @@
+package main
+
+import (
+	"weather/internal/clientip"
+	"weather/internal/data"
+	"weather/internal/drawing"
+	"weather/internal/repository"
+
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiErrorBody is the error envelope every API error is written in.
+type apiErrorBody struct {
+	Error struct {
+		Code     string            `json:"code"`
+		Message  string            `json:"message"`
+		Problems map[string]string `json:"problems"`
+	} `json:"error"`
+}
+
+func TestAPI(t *testing.T) {
+	ctx := context.Background()
+
+	sqlDB, err := createDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db := data.New(sqlDB)
+	observations := repository.NewObservationRepository(db, steadyWeather{}, time.Hour)
+	drawings := repository.NewDrawingRepository(db, drawing.DefaultLimits.Palette)
+
+	// Visitors in three cells have observed the weather.
+	for _, loc := range []data.Geolocation{
+		{Latitude: 52.52, Longitude: 13.42, Timezone: "UTC"},
+		{Latitude: 48.86, Longitude: 2.35, Timezone: "UTC"},
+		{Latitude: 40.42, Longitude: -3.7, Timezone: "UTC"},
+	} {
+		if _, err := observations.ForLocation(ctx, loc); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	clientIPs, err := clientip.NewResolver(nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	server := http.NewServeMux()
+	server.Handle("GET /api/v1/observations", handleAPIObservationsGet(observations))
+	server.Handle("GET /api/v1/observations/current", handleAPICurrentObservationGet(observations))
+	server.Handle("GET /api/v1/observations/{id}", handleAPIObservationGet(observations, drawings))
+	server.Handle("POST /api/v1/observations/{id}/drawing", handleAPIObservationDrawingPost(drawings, drawing.DefaultLimits))
+	server.Handle("GET /api/v1/geolocation", handleAPIGeolocationGet(clientIPs, invalidGeolocations{}))
+	server.Handle("/api/", handleAPINotFound())
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		return w
+	}
+
+	// expectError checks w is the error envelope with status and code.
+	expectError := func(t *testing.T, w *httptest.ResponseRecorder, status int, code string) apiErrorBody {
+		t.Helper()
+
+		body := apiErrorBody{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("expected the error envelope, got %q (%v)", w.Body.String(), err)
+		}
+		if w.Code != status || body.Error.Code != code || body.Error.Message == "" {
+			t.Errorf("expected %d %s, got %d %q", status, code, w.Code, w.Body.String())
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("expected JSON, got %q", contentType)
+		}
+		return body
+	}
+
+	list := func(t *testing.T, query string) observationsPage {
+		t.Helper()
+
+		w := request(http.MethodGet, "/api/v1/observations"+query, "")
+		page := observationsPage{}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %q (%v)", w.Code, w.Body.String(), err)
+		}
+		return page
+	}
+
+	ids := func(page observationsPage) []int64 {
+		var ids []int64
+		for _, row := range page.Observations {
+			ids = append(ids, row.Observation.ID)
+		}
+		return ids
+	}
+
+	t.Run("pages with next_before", func(t *testing.T) {
+		first := list(t, "?limit=2")
+		if got := ids(first); len(got) != 2 || got[0] != 3 || got[1] != 2 {
+			t.Fatalf("expected observations 3 and 2, got %v", got)
+		}
+		if first.NextBefore == nil || *first.NextBefore != 2 {
+			t.Fatalf("expected next_before 2, got %v", first.NextBefore)
+		}
+
+		last := list(t, "?limit=2&before=2")
+		if got := ids(last); len(got) != 1 || got[0] != 1 || last.NextBefore != nil {
+			t.Errorf("expected only observation 1 and no next_before, got %v, %v", got, last.NextBefore)
+		}
+	})
+
+	t.Run("refuses invalid filters", func(t *testing.T) {
+		w := request(http.MethodGet, "/api/v1/observations?limit=0&min_temp_c=warm", "")
+		body := expectError(t, w, http.StatusUnprocessableEntity, "invalid")
+		if body.Error.Problems["limit"] == "" || body.Error.Problems["min_temp_c"] == "" {
+			t.Errorf("expected problems with limit and min_temp_c, got %v", body.Error.Problems)
+		}
+	})
+
+	t.Run("can't find missing observations", func(t *testing.T) {
+		expectError(t, request(http.MethodGet, "/api/v1/observations/99", ""), http.StatusNotFound, "not_found")
+		expectError(t, request(http.MethodGet, "/api/v1/observations/nope", ""), http.StatusNotFound, "not_found")
+		expectError(t, request(http.MethodPost, "/api/v1/observations/99/drawing", `{"data": "`+testDrawing+`"}`), http.StatusNotFound, "not_found")
+	})
+
+	t.Run("can't find missing endpoints", func(t *testing.T) {
+		expectError(t, request(http.MethodGet, "/api/v1/nope", ""), http.StatusNotFound, "not_found")
+	})
+
+	t.Run("stores one drawing per observation", func(t *testing.T) {
+		w := request(http.MethodPost, "/api/v1/observations/1/drawing", `{"data": "`+testDrawing+`"}`)
+		if w.Code != http.StatusCreated || w.Header().Get("Location") != "/api/v1/observations/1/drawing" {
+			t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+		}
+
+		w = request(http.MethodPost, "/api/v1/observations/1/drawing", `{"data": "`+testDrawing+`"}`)
+		expectError(t, w, http.StatusConflict, "conflict")
+	})
+
+	t.Run("refuses invalid drawings", func(t *testing.T) {
+		body := expectError(t, request(http.MethodPost, "/api/v1/observations/2/drawing", `{"data": "not a drawing"}`), http.StatusUnprocessableEntity, "invalid")
+		if body.Error.Problems["data"] == "" {
+			t.Errorf("expected a problem with data, got %v", body.Error.Problems)
+		}
+
+		expectError(t, request(http.MethodPost, "/api/v1/observations/2/drawing", `{"drawing": "`+testDrawing+`"}`), http.StatusUnprocessableEntity, "invalid")
+	})
+
+	t.Run("serves the current observation where visitors have been", func(t *testing.T) {
+		w := request(http.MethodGet, "/api/v1/observations/current?lat=52.521&lon=13.419", "")
+		body := observationTemplateData{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %q (%v)", w.Code, w.Body.String(), err)
+		}
+		if body.Observation.ID != 1 {
+			t.Errorf("expected observation 1, got %d", body.Observation.ID)
+		}
+	})
+
+	t.Run("doesn't observe anywhere else", func(t *testing.T) {
+		w := request(http.MethodGet, "/api/v1/observations/current?lat=-33.87&lon=151.21", "")
+		expectError(t, w, http.StatusNotFound, "not_found")
+
+		if got := ids(list(t, "")); len(got) != 3 {
+			t.Errorf("expected no new observations, got %v", got)
+		}
+
+		w = request(http.MethodGet, "/api/v1/observations/current?lat=100&lon=0", "")
+		expectError(t, w, http.StatusUnprocessableEntity, "invalid")
+	})
+
+	t.Run("blames upstreams for their invalid responses", func(t *testing.T) {
+		body := expectError(t, request(http.MethodGet, "/api/v1/geolocation", ""), http.StatusBadGateway, "upstream")
+		if len(body.Error.Problems) > 0 || strings.Contains(body.Error.Message, "Mars") {
+			t.Errorf("expected the upstream's problems to stay private, got %+v", body.Error)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"weather/internal/database"
+	"weather/internal/migrate"
+
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+//go:embed sqlite/migrations/*.sql
+var migrationsFS embed.FS
+
+func loadMigrations() ([]migrate.Migration, error) {
+	dir, err := fs.Sub(migrationsFS, "sqlite/migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.Load(dir)
+}
+
+func createDatabase(path string) (*database.DB, error) {
+	db, err := database.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("couldn't load database migrations: %w", err)
+	}
+
+	done, err := migrate.Up(context.Background(), db.Write, migrations)
+	for _, m := range done {
+		log.Printf("applied migration %04d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("couldn't apply database migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+// runMigrateCommand implements `weather migrate [status|up]`.
+func runMigrateCommand(ctx context.Context, path string, args []string) error {
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	db, err := database.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "status":
+		statuses, err := migrate.Statuses(ctx, db.Write, migrations)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	case "up":
+		done, err := migrate.Up(ctx, db.Write, migrations)
+		for _, m := range done {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected status or up", command)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"weather/internal/validation"
+
+	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// observationFilters are the filters the gallery and the API both list
+// observations by.
+type observationFilters struct {
+	BeforeID sql.NullInt64
+	MinTempC sql.NullFloat64
+	MaxTempC sql.NullFloat64
+	Since    sql.NullTime
+	Until    sql.NullTime
+}
+
+// readObservationFilters reads the shared filters from query, recording
+// problems with v. Times are parsed with layout, and when that's a date,
+// until includes the whole day.
+func readObservationFilters(v *validation.Validator, query url.Values, layout string) observationFilters {
+	filters := observationFilters{}
+
+	if raw := strings.TrimSpace(query.Get("before")); raw != "" {
+		before, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || before < 1 {
+			v.Problem("before", "must be an observation ID")
+		} else {
+			filters.BeforeID = sql.NullInt64{Int64: before, Valid: true}
+		}
+	}
+
+	readTemperature := func(field string) sql.NullFloat64 {
+		raw := strings.TrimSpace(query.Get(field))
+		if raw == "" {
+			return sql.NullFloat64{}
+		}
+
+		temp, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			v.Problem(field, "must be a number")
+			return sql.NullFloat64{}
+		}
+
+		return sql.NullFloat64{Float64: temp, Valid: validation.Field(v, field, temp, validation.Between(-100.0, 100.0))}
+	}
+	filters.MinTempC = readTemperature("min_temp_c")
+	filters.MaxTempC = readTemperature("max_temp_c")
+
+	kind := "time"
+	if layout == time.DateOnly {
+		kind = "date"
+	}
+	example := time.Date(2024, 11, 4, 12, 0, 0, 0, time.UTC).Format(layout)
+
+	readTime := func(field string) sql.NullTime {
+		raw := strings.TrimSpace(query.Get(field))
+		if raw == "" {
+			return sql.NullTime{}
+		}
+
+		t, err := time.Parse(layout, raw)
+		if err != nil {
+			v.Problem(field, "must be a %s, like %s", kind, example)
+			return sql.NullTime{}
+		}
+
+		return sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+	filters.Since = readTime("since")
+	filters.Until = readTime("until")
+	if filters.Until.Valid && layout == time.DateOnly {
+		filters.Until.Time = filters.Until.Time.AddDate(0, 0, 1)
+	}
+
+	if filters.MinTempC.Valid && filters.MaxTempC.Valid && filters.MinTempC.Float64 > filters.MaxTempC.Float64 {
+		v.Problem("min_temp_c", "must not be above max_temp_c")
+	}
+	if filters.Since.Valid && filters.Until.Valid && !filters.Since.Time.Before(filters.Until.Time) {
+		v.Problem("since", "must be before until")
+	}
+
+	return filters
+}
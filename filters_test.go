@@ -0,0 +1,66 @@
+package main
+
+import (
+	"weather/internal/validation"
+
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestReadObservationFilters(t *testing.T) {
+	read := func(query string, layout string) (observationFilters, error) {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		v := validation.Validator{}
+		filters := readObservationFilters(&v, values, layout)
+		return filters, v.Err()
+	}
+
+	t.Run("reads every filter", func(t *testing.T) {
+		filters, err := read("before=7&min_temp_c=-5&max_temp_c=30.5&since=2024-11-04T12:00:00Z&until=2024-11-05T00:00:00Z", time.RFC3339)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if filters.BeforeID.Int64 != 7 || filters.MinTempC.Float64 != -5 || filters.MaxTempC.Float64 != 30.5 {
+			t.Errorf("unexpected filters %+v", filters)
+		}
+		if !filters.Until.Time.Equal(time.Date(2024, 11, 5, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected a time to be used as it is, got %v", filters.Until.Time)
+		}
+	})
+
+	t.Run("until covers the date it names", func(t *testing.T) {
+		filters, err := read("since=2024-11-04&until=2024-11-04", time.DateOnly)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if !filters.Until.Time.Equal(time.Date(2024, 11, 5, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected until to be the start of the next day, got %v", filters.Until.Time)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		query string
+		field string
+	}{
+		"bad cursor":         {"before=0", "before"},
+		"bad temperature":    {"min_temp_c=warm", "min_temp_c"},
+		"temperature range":  {"max_temp_c=150", "max_temp_c"},
+		"crossed range":      {"min_temp_c=10&max_temp_c=5", "min_temp_c"},
+		"bad date":           {"since=yesterday", "since"},
+		"dates out of order": {"since=2024-11-05&until=2024-11-04", "since"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := read(tc.query, time.DateOnly)
+			if problems := validation.ProblemsOf(err, "filters"); len(problems[tc.field]) == 0 {
+				t.Errorf("expected a problem with %s, got %v", tc.field, err)
+			}
+		})
+	}
+}
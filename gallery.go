@@ -0,0 +1,234 @@
+package main
+
+import (
+	"weather/internal/cookie"
+	"weather/internal/data"
+	"weather/internal/repository"
+	"weather/internal/templates"
+	"weather/internal/validation"
+	"weather/internal/weather"
+
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const galleryPageSize = 12
+
+// galleryDateLayout is how date inputs submit their values.
+const galleryDateLayout = time.DateOnly
+
+// galleryFilters are the gallery's filters as submitted, to refill the
+// filter form.
+type galleryFilters struct {
+	Category string
+	MinTempC string
+	MaxTempC string
+	Country  string
+	Since    string
+	Until    string
+}
+
+func (f galleryFilters) query() url.Values {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"category":   f.Category,
+		"min_temp_c": f.MinTempC,
+		"max_temp_c": f.MaxTempC,
+		"country":    f.Country,
+		"since":      f.Since,
+		"until":      f.Until,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	return query
+}
+
+// galleryPage is a page of drawn observations, newest first. Next fetches
+// the following page, and is empty on the last one.
+type galleryPage struct {
+	Observations []observationTemplateData
+	Next         string
+}
+
+// readGalleryFilters reads the gallery's filters from query. Dates are
+// whole days, so until includes the day it names.
+func readGalleryFilters(query url.Values) (data.ListDrawnObservationsParams, galleryFilters, error) {
+	v := validation.Validator{}
+	params := data.ListDrawnObservationsParams{Limit: galleryPageSize + 1}
+	filters := galleryFilters{
+		Category: strings.TrimSpace(query.Get("category")),
+		MinTempC: strings.TrimSpace(query.Get("min_temp_c")),
+		MaxTempC: strings.TrimSpace(query.Get("max_temp_c")),
+		Country:  strings.TrimSpace(query.Get("country")),
+		Since:    strings.TrimSpace(query.Get("since")),
+		Until:    strings.TrimSpace(query.Get("until")),
+	}
+
+	shared := readObservationFilters(&v, query, galleryDateLayout)
+	params.BeforeID = shared.BeforeID
+	params.MinTempC = shared.MinTempC
+	params.MaxTempC = shared.MaxTempC
+	params.Since = shared.Since
+	params.Until = shared.Until
+
+	if filters.Category != "" {
+		category, ok := weather.LookupCategory(filters.Category)
+		if !ok {
+			v.Problem("category", "isn't a kind of weather we know")
+		}
+		params.WeatherCategory = sql.NullString{String: category.Name, Valid: true}
+	}
+
+	if filters.Country != "" {
+		params.Country = sql.NullString{String: filters.Country, Valid: true}
+	}
+
+	return params, filters, v.Err()
+}
+
+// resolveGalleryPage reads a page of drawn observations, linking to the next
+// page when there is one.
+func resolveGalleryPage(
+	ctx context.Context,
+	params data.ListDrawnObservationsParams,
+	filters galleryFilters,
+	units weather.Units,
+	drawings repository.DrawingRepository,
+) (galleryPage, error) {
+	rows, err := drawings.List(ctx, params)
+	if err != nil {
+		return galleryPage{}, err
+	}
+
+	page := galleryPage{}
+	for i := range rows {
+		if i == galleryPageSize {
+			query := filters.query()
+			query.Set("before", strconv.FormatInt(rows[i-1].Observation.ID, 10))
+			page.Next = "/observations?" + query.Encode()
+			break
+		}
+
+		page.Observations = append(page.Observations, observationTemplateData{
+			Observation: rows[i].Observation,
+			Drawing:     &rows[i].ObservationDrawing,
+			Units:       units,
+		})
+	}
+
+	return page, nil
+}
+
+// galleryCategory is a weather category and how many drawn observations it
+// has.
+type galleryCategory struct {
+	weather.Category
+	Observations int64
+}
+
+// countGalleryCategories lists every weather category with how many drawn
+// observations it has.
+func countGalleryCategories(ctx context.Context, drawings repository.DrawingRepository) ([]galleryCategory, error) {
+	rows, err := drawings.CountByCategory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.WeatherCategory] = row.Observations
+	}
+
+	categories := make([]galleryCategory, len(weather.Categories))
+	for i, category := range weather.Categories {
+		categories[i] = galleryCategory{Category: category, Observations: counts[category.Name]}
+	}
+
+	return categories, nil
+}
+
+// handleObservationsGet shows drawn observations, newest first. Later pages
+// are requested by htmx as the visitor scrolls and only render the
+// observations.
+func handleObservationsGet(
+	tmpl *templates.TemplateEngine,
+	signer *cookie.Signer,
+	observations repository.ObservationRepository,
+	drawings repository.DrawingRepository,
+) http.Handler {
+	const observationsTemplateName = "templates/observations.template.html"
+	const galleryPageFragmentName = "observation-gallery-page"
+
+	type observationsTemplateData struct {
+		Filters    galleryFilters
+		Categories []galleryCategory
+		Countries  []string
+		Problems   validation.ValidationProblems
+		Page       galleryPage
+		Units      weather.Units
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		params, filters, err := readGalleryFilters(query)
+		if err != nil && query.Has("before") {
+			validation.WriteError(w, r, err, "filters", tmpl, ".observation-gallery-next")
+			return
+		}
+
+		templateData := observationsTemplateData{
+			Filters: filters,
+			Units:   resolveUnits(r, signer),
+		}
+
+		if err != nil {
+			templateData.Problems = validation.ProblemsOf(err, "filters")
+		} else {
+			templateData.Page, err = resolveGalleryPage(ctx, params, filters, templateData.Units, drawings)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Printf("error listing drawn observations: %v", err)
+					http.Error(w, "uh oh, I beefed it :(", http.StatusInternalServerError)
+				}
+				return
+			}
+		}
+
+		if r.Header.Get("HX-Request") == "true" && query.Has("before") {
+			if err := tmpl.RenderFragment(w, galleryPageFragmentName, templateData.Page); err != nil {
+				log.Printf("error rendering gallery page: %v", err)
+			}
+			return
+		}
+
+		templateData.Countries, err = observations.Countries(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("error listing observation countries: %v", err)
+		}
+
+		templateData.Categories, err = countGalleryCategories(ctx, drawings)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("error counting observations by category: %v", err)
+		}
+
+		if templateData.Problems != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		if err := tmpl.Render(w, observationsTemplateName, templateData); err != nil {
+			log.Printf("error rendering observations template: %v", err)
+			return
+		}
+	})
+}
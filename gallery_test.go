@@ -0,0 +1,115 @@
+package main
+
+import (
+	"weather/internal/data"
+	"weather/internal/drawing"
+	"weather/internal/repository"
+	"weather/internal/weather"
+
+	"context"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestResolveGalleryPage(t *testing.T) {
+	ctx := context.Background()
+
+	sqlDB, err := createDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db := data.New(sqlDB)
+	drawings := repository.NewDrawingRepository(db, drawing.DefaultLimits.Palette)
+
+	// draw stores a drawn observation made at observed, each in its own cell.
+	draw := func(observed time.Time) int64 {
+		t.Helper()
+
+		obs, err := db.UpsertObservation(ctx, data.UpsertObservationParams{
+			Timezone:  "UTC",
+			TimeUtc:   observed,
+			TimeLocal: observed,
+			CellLat:   observed.Unix(),
+			BucketUtc: observed,
+		})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if err := drawings.Add(ctx, data.ObservationDrawing{ObservationID: obs.ID, Data: testDrawing, TimeSubmitted: observed}); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return obs.ID
+	}
+
+	october := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	ids := make([]int64, galleryPageSize+1)
+	for i := range ids {
+		ids[i] = draw(october.Add(time.Duration(i) * time.Hour))
+	}
+
+	lateOnNov4 := draw(time.Date(2024, 11, 4, 23, 30, 0, 0, time.UTC))
+	earlyOnNov5 := draw(time.Date(2024, 11, 5, 0, 30, 0, 0, time.UTC))
+
+	resolve := func(query url.Values) galleryPage {
+		t.Helper()
+
+		params, filters, err := readGalleryFilters(query)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		page, err := resolveGalleryPage(ctx, params, filters, weather.Units{}, drawings)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return page
+	}
+
+	pageIDs := func(page galleryPage) []int64 {
+		var ids []int64
+		for _, obs := range page.Observations {
+			ids = append(ids, obs.Observation.ID)
+		}
+		return ids
+	}
+
+	t.Run("continues after the last observation shown", func(t *testing.T) {
+		first := resolve(url.Values{"since": {"2024-10-01"}, "until": {"2024-10-31"}})
+		if got := pageIDs(first); len(got) != galleryPageSize || got[0] != ids[galleryPageSize] || got[galleryPageSize-1] != ids[1] {
+			t.Fatalf("expected the newest %d of October, got %v", galleryPageSize, got)
+		}
+
+		next, err := url.Parse(first.Next)
+		if err != nil || next.Path != "/observations" {
+			t.Fatalf("unexpected next page %q", first.Next)
+		}
+		query := next.Query()
+		if query.Get("before") != strconv.FormatInt(ids[1], 10) || query.Get("since") != "2024-10-01" || query.Get("until") != "2024-10-31" {
+			t.Errorf("expected the next page to keep the filters and start before %d, got %q", ids[1], first.Next)
+		}
+
+		last := resolve(query)
+		if got := pageIDs(last); len(got) != 1 || got[0] != ids[0] || last.Next != "" {
+			t.Errorf("expected only the oldest observation and no next page, got %v, %q", got, last.Next)
+		}
+	})
+
+	t.Run("until includes the whole day", func(t *testing.T) {
+		page := resolve(url.Values{"since": {"2024-11-04"}, "until": {"2024-11-04"}})
+		if got := pageIDs(page); len(got) != 1 || got[0] != lateOnNov4 {
+			t.Errorf("expected only %d, got %v", lateOnNov4, got)
+		}
+
+		page = resolve(url.Values{"since": {"2024-11-05"}})
+		if got := pageIDs(page); len(got) != 1 || got[0] != earlyOnNov5 {
+			t.Errorf("expected only %d, got %v", earlyOnNov5, got)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTL(t *testing.T) {
+	now := time.Now()
+
+	c := NewTTL[string, int](time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+
+	t.Run("returns fresh entries", func(t *testing.T) {
+		if v, ok := c.Get("a"); !ok || v != 1 {
+			t.Errorf("expected 1, got %v (ok=%v)", v, ok)
+		}
+	})
+
+	t.Run("expires stale entries", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		if _, ok := c.Get("a"); ok {
+			t.Errorf("expected entry to have expired")
+		}
+	})
+
+	t.Run("sweeps entries that are never read again", func(t *testing.T) {
+		c := NewTTL[int, int](time.Minute)
+		c.now = func() time.Time { return now }
+
+		for i := range 10 {
+			c.Set(i, i)
+		}
+
+		now = now.Add(time.Minute)
+		c.Set(10, 10)
+
+		if n := len(c.entries); n != 1 {
+			t.Errorf("expected only the fresh entry to be left, got %d", n)
+		}
+	})
+}
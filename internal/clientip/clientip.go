@@ -0,0 +1,173 @@
+package clientip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var ErrNoAddress = errors.New("couldn't determine client address")
+
+// Resolver finds the address of the client that made a request. Forwarding
+// headers are only believed when they were added by a trusted proxy.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver builds a Resolver that trusts forwarding headers from proxies
+// in the given CIDRs. Bare addresses are treated as single host prefixes.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, cidr := range trustedProxies {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+			}
+			r.trusted = append(r.trusted, netip.PrefixFrom(Normalize(addr), Normalize(addr).BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	return r, nil
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the normalized address of the client behind req.
+//
+// Starting from the peer address, each hop is trusted to report the one
+// before it for as long as it is a trusted proxy. Forwarded (RFC 7239) is
+// preferred over X-Forwarded-For, which is preferred over X-Real-IP.
+func (r *Resolver) ClientIP(req *http.Request) (netip.Addr, error) {
+	remote, err := parseNode(req.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%w: %w", ErrNoAddress, err)
+	}
+
+	if !r.isTrusted(remote) {
+		return remote, nil
+	}
+
+	var chain []string
+	switch {
+	case len(req.Header.Values("Forwarded")) > 0:
+		chain = forwardedFor(req.Header.Values("Forwarded"))
+	case len(req.Header.Values("X-Forwarded-For")) > 0:
+		for _, header := range req.Header.Values("X-Forwarded-For") {
+			for _, node := range strings.Split(header, ",") {
+				chain = append(chain, strings.TrimSpace(node))
+			}
+		}
+	case req.Header.Get("X-Real-IP") != "":
+		chain = []string{strings.TrimSpace(req.Header.Get("X-Real-IP"))}
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, err := parseNode(chain[i])
+		if err != nil {
+			// Obfuscated or unknown nodes can't be traced any further.
+			break
+		}
+
+		client = addr
+		if !r.isTrusted(addr) {
+			break
+		}
+	}
+
+	return client, nil
+}
+
+// Normalize strips zones and unmaps IPv4-mapped IPv6 addresses so the same
+// client always has the same key.
+func Normalize(addr netip.Addr) netip.Addr {
+	return addr.WithZone("").Unmap()
+}
+
+// parseNode parses a node as it appears in RemoteAddr, X-Forwarded-For or a
+// Forwarded "for" parameter: an address, optionally bracketed and
+// optionally followed by a port.
+func parseNode(node string) (netip.Addr, error) {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+
+	if addr, err := netip.ParseAddr(node); err == nil {
+		return Normalize(addr), nil
+	}
+
+	host, _, err := net.SplitHostPort(node)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid address %q", node)
+	}
+
+	return Normalize(addr), nil
+}
+
+// forwardedFor returns the "for" parameter of each element of the given
+// Forwarded headers, in order.
+//
+// https://www.rfc-editor.org/rfc/rfc7239#section-4
+func forwardedFor(headers []string) []string {
+	var nodes []string
+	for _, header := range headers {
+		for _, element := range splitQuoted(header, ',') {
+			for _, pair := range splitQuoted(element, ';') {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					nodes = append(nodes, value)
+				}
+			}
+		}
+	}
+
+	return nodes
+}
+
+// splitQuoted splits s on sep, ignoring separators inside quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
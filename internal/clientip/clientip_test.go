@@ -0,0 +1,85 @@
+package clientip
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewResolver([]string{"10.0.0.0/8", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	cases := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"ipv4 peer", "203.0.113.7:5555", nil, "203.0.113.7"},
+		{"ipv6 peer", "[2001:db8::1]:5555", nil, "2001:db8::1"},
+		{"ipv6 peer with zone", "[fe80::1%eth0]:5555", nil, "fe80::1"},
+		{"ipv4 mapped peer", "[::ffff:203.0.113.7]:5555", nil, "203.0.113.7"},
+		{
+			"ignores headers from untrusted peers",
+			"203.0.113.7:5555",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			"203.0.113.7",
+		},
+		{
+			"x-forwarded-for through trusted proxies",
+			"10.0.0.1:5555",
+			map[string]string{"X-Forwarded-For": "192.0.2.1, 198.51.100.1, 10.0.0.2"},
+			"198.51.100.1",
+		},
+		{
+			"x-real-ip",
+			"10.0.0.1:5555",
+			map[string]string{"X-Real-IP": "198.51.100.1"},
+			"198.51.100.1",
+		},
+		{
+			"forwarded with quoted ipv6 and port",
+			"[fd00::1]:5555",
+			map[string]string{"Forwarded": `for=192.0.2.43, for="[2001:db8:cafe::17]:4711";proto=https`},
+			"2001:db8:cafe::17",
+		},
+		{
+			"forwarded takes precedence",
+			"10.0.0.1:5555",
+			map[string]string{"Forwarded": "for=192.0.2.60;proto=http;by=203.0.113.43", "X-Forwarded-For": "198.51.100.1"},
+			"192.0.2.60",
+		},
+		{
+			"stops at obfuscated nodes",
+			"10.0.0.1:5555",
+			map[string]string{"Forwarded": "for=192.0.2.60, for=_hidden"},
+			"10.0.0.1",
+		},
+		{
+			"all trusted falls back to the first hop",
+			"10.0.0.1:5555",
+			map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			"10.0.0.3",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = c.remote
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+
+			got, err := resolver.ClientIP(r)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if got.String() != c.want {
+				t.Errorf("expected %s, got %s", c.want, got)
+			}
+		})
+	}
+}
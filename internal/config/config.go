@@ -0,0 +1,388 @@
+package config
+
+import (
+	"weather/internal/drawing"
+	"weather/internal/fetch"
+	"weather/internal/location"
+	"weather/internal/weather"
+
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is everything the server can be configured with. Each field can be
+// set from a YAML file, an environment variable or a command-line flag, in
+// increasing order of precedence. Nested keys are joined to form the names
+// of the other two, so drawing.max_bytes is WEATHER_DRAWING_MAX_BYTES and
+// -drawing-max-bytes.
+type Config struct {
+	Addr           string         `yaml:"addr" usage:"address to listen on"`
+	MetricsAddr    string         `yaml:"metrics_addr" usage:"address to serve /metrics on, apart from addr so it isn't public, empty disables metrics"`
+	DBPath         string         `yaml:"db_path" usage:"path to the SQLite database"`
+	CookieSecret   string         `yaml:"cookie_secret" usage:"key used to sign cookies, a random key is generated when empty"`
+	TrustedProxies []string       `yaml:"trusted_proxies" usage:"CIDRs of reverse proxies whose forwarding headers are trusted"`
+	Server         ServerConfig   `yaml:"server"`
+	Upstream       UpstreamConfig `yaml:"upstream"`
+	Location       LocationConfig `yaml:"location"`
+	Weather        WeatherConfig  `yaml:"weather"`
+	Drawing        DrawingConfig  `yaml:"drawing"`
+}
+
+type ServerConfig struct {
+	ReadTimeout       Duration `yaml:"read_timeout" usage:"longest time to read a request, including its body"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" usage:"longest time to read request headers"`
+	WriteTimeout      Duration `yaml:"write_timeout" usage:"longest time to write a response"`
+	IdleTimeout       Duration `yaml:"idle_timeout" usage:"longest time to keep an idle connection open"`
+	ShutdownTimeout   Duration `yaml:"shutdown_timeout" usage:"longest time to wait for in-flight requests when shutting down"`
+	MaxHeaderBytes    int      `yaml:"max_header_bytes" usage:"largest request header accepted, in bytes"`
+}
+
+type UpstreamConfig struct {
+	Timeout      Duration `yaml:"timeout" usage:"longest time an outbound request may take"`
+	HostTimeouts []string `yaml:"host_timeouts" usage:"per-host outbound timeouts, as host=duration"`
+
+	RetryAttempts  int      `yaml:"retry_attempts" usage:"how many times a failed outbound GET is tried, 1 disables retries"`
+	RetryBaseDelay Duration `yaml:"retry_base_delay" usage:"delay before the first retry, doubled for each one after"`
+	RetryMaxDelay  Duration `yaml:"retry_max_delay" usage:"longest delay between retries, hosts asking for longer aren't retried"`
+
+	BreakerWindow         int      `yaml:"breaker_window" usage:"how many recent requests to a host its circuit breaker considers"`
+	BreakerMinRequests    int      `yaml:"breaker_min_requests" usage:"fewest recent requests before a host's circuit breaker may open"`
+	BreakerFailurePercent int      `yaml:"breaker_failure_percent" usage:"percentage of recent requests that must fail to open a host's circuit breaker"`
+	BreakerCooldown       Duration `yaml:"breaker_cooldown" usage:"how long an open circuit breaker rejects requests before probing the host"`
+}
+
+// RetryPolicy converts the retry configuration for package fetch.
+func (c UpstreamConfig) RetryPolicy() fetch.RetryPolicy {
+	return fetch.RetryPolicy{
+		Attempts:  c.RetryAttempts,
+		BaseDelay: time.Duration(c.RetryBaseDelay),
+		MaxDelay:  time.Duration(c.RetryMaxDelay),
+	}
+}
+
+// BreakerPolicy converts the circuit breaker configuration for package fetch.
+func (c UpstreamConfig) BreakerPolicy() fetch.BreakerPolicy {
+	return fetch.BreakerPolicy{
+		Window:       c.BreakerWindow,
+		MinRequests:  c.BreakerMinRequests,
+		FailureRatio: float64(c.BreakerFailurePercent) / 100,
+		Cooldown:     time.Duration(c.BreakerCooldown),
+	}
+}
+
+// Timeouts parses the per-host timeouts, which Validate has checked.
+func (c UpstreamConfig) Timeouts() map[string]time.Duration {
+	timeouts, _ := fetch.ParseHostTimeouts(c.HostTimeouts)
+	return timeouts
+}
+
+// LocationConfig configures IP geolocation. Without a list of providers,
+// visitors are looked up in the mmdb database when mmdb_path is set, falling
+// back to ipapi, and with ipapi alone otherwise. ipapi needs nothing set up,
+// but its free endpoint is plain HTTP, so every visitor's IP address crosses
+// the network unencrypted to a third party. A local GeoLite2 database keeps
+// addresses on the server, at the cost of downloading it and keeping it up
+// to date.
+type LocationConfig struct {
+	Providers     []string `yaml:"providers" usage:"IP geolocation providers, tried in order (mmdb, ipapi), defaults to mmdb then ipapi when mmdb_path is set and ipapi otherwise"`
+	MMDBPath      string   `yaml:"mmdb_path" usage:"path to a GeoLite2-City format database for the mmdb provider"`
+	IPAPIBasePath string   `yaml:"ipapi_base_path" usage:"base URL of the ip-api.com JSON endpoint"`
+	TTL           Duration `yaml:"ttl" usage:"how long an IP's geolocation is reused before it is looked up again"`
+}
+
+type WeatherConfig struct {
+	Providers         []string `yaml:"providers" usage:"weather providers, tried in order (openmeteo, metno), of which only openmeteo also forecasts"`
+	OpenMeteoBasePath string   `yaml:"openmeteo_base_path" usage:"base URL of the Open-Meteo forecast endpoint"`
+	METNorwayBasePath string   `yaml:"metno_base_path" usage:"base URL of the MET Norway locationforecast endpoint"`
+	TTL               Duration `yaml:"ttl" usage:"how long an observation is reused for the same location"`
+	ForecastTTL       Duration `yaml:"forecast_ttl" usage:"how long a forecast is reused for the same location"`
+}
+
+type DrawingConfig struct {
+	Width      int      `yaml:"width" usage:"drawing canvas width in pixels"`
+	Height     int      `yaml:"height" usage:"drawing canvas height in pixels"`
+	MaxBytes   int      `yaml:"max_bytes" usage:"largest encoded drawing accepted, in bytes"`
+	MaxStrokes int      `yaml:"max_strokes" usage:"most strokes a drawing may contain"`
+	Colors     []string `yaml:"colors" usage:"palette colors, as #rgb or #rrggbb"`
+	BrushSizes []int    `yaml:"brush_sizes" usage:"palette brush radii in pixels"`
+}
+
+// Limits converts the drawing configuration for package drawing.
+func (c DrawingConfig) Limits() drawing.Limits {
+	return drawing.Limits{
+		Width:      uint16(c.Width),
+		Height:     uint16(c.Height),
+		MaxBytes:   c.MaxBytes,
+		MaxStrokes: c.MaxStrokes,
+		Palette: drawing.Palette{
+			Colors:     c.Colors,
+			BrushSizes: c.BrushSizes,
+		},
+	}
+}
+
+// ProviderNames is Providers, or the default chain when none are listed.
+func (c LocationConfig) ProviderNames() []string {
+	switch {
+	case len(c.Providers) > 0:
+		return c.Providers
+	case c.MMDBPath != "":
+		return []string{"mmdb", "ipapi"}
+	default:
+		return []string{"ipapi"}
+	}
+}
+
+// Duration is a time.Duration written as a string like "15m" in YAML.
+type Duration time.Duration
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+func Default() Config {
+	limits := drawing.DefaultLimits
+
+	return Config{
+		Addr:        "localhost:8080",
+		MetricsAddr: "localhost:9090",
+		DBPath:      "./db.sqlite",
+		Server: ServerConfig{
+			ReadTimeout:       Duration(10 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			ShutdownTimeout:   Duration(30 * time.Second),
+			MaxHeaderBytes:    1 << 20,
+		},
+		Upstream: UpstreamConfig{
+			Timeout: Duration(10 * time.Second),
+			HostTimeouts: []string{
+				"ip-api.com=3s",
+				"api.open-meteo.com=5s",
+				"api.met.no=5s",
+			},
+			RetryAttempts:         fetch.DefaultRetryPolicy.Attempts,
+			RetryBaseDelay:        Duration(fetch.DefaultRetryPolicy.BaseDelay),
+			RetryMaxDelay:         Duration(fetch.DefaultRetryPolicy.MaxDelay),
+			BreakerWindow:         fetch.DefaultBreakerPolicy.Window,
+			BreakerMinRequests:    fetch.DefaultBreakerPolicy.MinRequests,
+			BreakerFailurePercent: int(fetch.DefaultBreakerPolicy.FailureRatio * 100),
+			BreakerCooldown:       Duration(fetch.DefaultBreakerPolicy.Cooldown),
+		},
+		Location: LocationConfig{
+			IPAPIBasePath: location.IPAPIBasePath,
+			TTL:           Duration(24 * time.Hour),
+		},
+		Weather: WeatherConfig{
+			Providers:         []string{"openmeteo", "metno"},
+			OpenMeteoBasePath: weather.OpenMeteoBasePath,
+			METNorwayBasePath: weather.METNorwayBasePath,
+			TTL:               Duration(15 * time.Minute),
+			ForecastTTL:       Duration(time.Hour),
+		},
+		Drawing: DrawingConfig{
+			Width:      int(limits.Width),
+			Height:     int(limits.Height),
+			MaxBytes:   limits.MaxBytes,
+			MaxStrokes: limits.MaxStrokes,
+			Colors:     append([]string{}, limits.Palette.Colors...),
+			BrushSizes: append([]int{}, limits.Palette.BrushSizes...),
+		},
+	}
+}
+
+// LoadFile overlays the YAML file at path onto c.
+func (c *Config) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every problem with c, one per line.
+func (c Config) Validate() error {
+	var errs []error
+	problem := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		problem("addr", "must be host:port, got %q", c.Addr)
+	}
+	if c.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			problem("metrics_addr", "must be host:port or empty, got %q", c.MetricsAddr)
+		} else if c.MetricsAddr == c.Addr {
+			problem("metrics_addr", "must differ from addr")
+		}
+	}
+
+	if c.DBPath == "" {
+		problem("db_path", "must be set")
+	}
+
+	for _, timeout := range []struct {
+		key   string
+		value Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	} {
+		if timeout.value <= 0 {
+			problem(timeout.key, "must be positive")
+		}
+	}
+
+	if c.Server.MaxHeaderBytes < 1 {
+		problem("server.max_header_bytes", "must be positive")
+	}
+
+	if c.Upstream.Timeout <= 0 {
+		problem("upstream.timeout", "must be positive")
+	}
+	if _, err := fetch.ParseHostTimeouts(c.Upstream.HostTimeouts); err != nil {
+		problem("upstream.host_timeouts", "%v", err)
+	}
+	if c.Upstream.RetryAttempts < 1 {
+		problem("upstream.retry_attempts", "must be at least 1")
+	}
+	if c.Upstream.RetryBaseDelay <= 0 {
+		problem("upstream.retry_base_delay", "must be positive")
+	}
+	if c.Upstream.RetryMaxDelay < c.Upstream.RetryBaseDelay {
+		problem("upstream.retry_max_delay", "must be at least upstream.retry_base_delay")
+	}
+	if c.Upstream.BreakerWindow < 1 {
+		problem("upstream.breaker_window", "must be positive")
+	}
+	if c.Upstream.BreakerMinRequests < 1 || c.Upstream.BreakerMinRequests > c.Upstream.BreakerWindow {
+		problem("upstream.breaker_min_requests", "must be between 1 and upstream.breaker_window")
+	}
+	if c.Upstream.BreakerFailurePercent < 1 || c.Upstream.BreakerFailurePercent > 100 {
+		problem("upstream.breaker_failure_percent", "must be between 1 and 100, got %d", c.Upstream.BreakerFailurePercent)
+	}
+	if c.Upstream.BreakerCooldown <= 0 {
+		problem("upstream.breaker_cooldown", "must be positive")
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				problem("trusted_proxies", "%q is not a CIDR or IP address", proxy)
+			}
+		}
+	}
+
+	for _, p := range c.Location.ProviderNames() {
+		if p == "mmdb" && c.Location.MMDBPath == "" {
+			problem("location.mmdb_path", "must be set to use the mmdb provider")
+		}
+	}
+	checkURL(problem, "location.ipapi_base_path", c.Location.IPAPIBasePath)
+	if c.Location.TTL <= 0 {
+		problem("location.ttl", "must be positive")
+	}
+
+	if len(c.Weather.Providers) == 0 {
+		problem("weather.providers", "must list at least one provider")
+	}
+	checkURL(problem, "weather.openmeteo_base_path", c.Weather.OpenMeteoBasePath)
+	checkURL(problem, "weather.metno_base_path", c.Weather.METNorwayBasePath)
+	if c.Weather.TTL <= 0 {
+		problem("weather.ttl", "must be positive")
+	}
+	if c.Weather.ForecastTTL <= 0 {
+		problem("weather.forecast_ttl", "must be positive")
+	}
+
+	if c.Drawing.Width < 1 || c.Drawing.Width > 0xffff {
+		problem("drawing.width", "must be between 1 and 65535, got %d", c.Drawing.Width)
+	}
+	if c.Drawing.Height < 1 || c.Drawing.Height > 0xffff {
+		problem("drawing.height", "must be between 1 and 65535, got %d", c.Drawing.Height)
+	}
+	if c.Drawing.MaxBytes < 1 {
+		problem("drawing.max_bytes", "must be positive")
+	}
+	if c.Drawing.MaxStrokes < 1 {
+		problem("drawing.max_strokes", "must be positive")
+	}
+	if len(c.Drawing.Colors) == 0 || len(c.Drawing.Colors) > 256 {
+		problem("drawing.colors", "must list between 1 and 256 colors")
+	}
+	if err := drawing.CheckPalette(c.Drawing.Limits().Palette); err != nil {
+		problem("drawing", "%v", err)
+	}
+	if len(c.Drawing.BrushSizes) == 0 || len(c.Drawing.BrushSizes) > 256 {
+		problem("drawing.brush_sizes", "must list between 1 and 256 sizes")
+	}
+	for _, size := range c.Drawing.BrushSizes {
+		if size < 1 {
+			problem("drawing.brush_sizes", "sizes must be positive, got %d", size)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func checkURL(problem func(string, string, ...any), key string, value string) {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		problem(key, "must be an absolute URL, got %q", value)
+	}
+}
+
+// WriteYAML writes c as YAML, with secrets redacted.
+func (c Config) WriteYAML(w io.Writer) error {
+	if c.CookieSecret != "" {
+		c.CookieSecret = "REDACTED"
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func envName(key string) string {
+	return "WEATHER_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+func flagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
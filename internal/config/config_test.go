@@ -0,0 +1,117 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`
+addr: file:1
+db_path: file.sqlite
+weather:
+  ttl: 5m
+drawing:
+  brush_sizes: [2, 4]
+`), 0o600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	env := map[string]string{
+		"WEATHER_CONFIG":  path,
+		"WEATHER_DB_PATH": "env.sqlite",
+		"WEATHER_ADDR":    "env:1",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	inv, err := Load("weather", []string{"-addr", "flag:1", "-trusted-proxies", "10.0.0.0/8, ::1", "migrate", "up"}, lookupEnv, io.Discard)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	cfg := inv.Config
+
+	t.Run("flags override environment", func(t *testing.T) {
+		if cfg.Addr != "flag:1" {
+			t.Errorf("expected flag:1, got %s", cfg.Addr)
+		}
+	})
+
+	t.Run("environment overrides file", func(t *testing.T) {
+		if cfg.DBPath != "env.sqlite" {
+			t.Errorf("expected env.sqlite, got %s", cfg.DBPath)
+		}
+	})
+
+	t.Run("file overrides defaults", func(t *testing.T) {
+		if time.Duration(cfg.Weather.TTL) != 5*time.Minute {
+			t.Errorf("expected 5m, got %v", time.Duration(cfg.Weather.TTL))
+		}
+		if len(cfg.Drawing.BrushSizes) != 2 || cfg.Drawing.BrushSizes[1] != 4 {
+			t.Errorf("expected [2 4], got %v", cfg.Drawing.BrushSizes)
+		}
+		if len(cfg.Drawing.Colors) != 3 {
+			t.Errorf("expected default colors to be kept, got %v", cfg.Drawing.Colors)
+		}
+	})
+
+	t.Run("parses lists", func(t *testing.T) {
+		if strings.Join(cfg.TrustedProxies, " ") != "10.0.0.0/8 ::1" {
+			t.Errorf("unexpected trusted proxies: %v", cfg.TrustedProxies)
+		}
+	})
+
+	t.Run("leaves positional arguments", func(t *testing.T) {
+		if strings.Join(inv.Args, " ") != "migrate up" {
+			t.Errorf("unexpected args: %v", inv.Args)
+		}
+	})
+
+	t.Run("is valid", func(t *testing.T) {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Addr = "nowhere"
+	cfg.MetricsAddr = "nowhere"
+	cfg.Location.Providers = []string{"mmdb"}
+	cfg.Drawing.Colors = []string{"red"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	for _, key := range []string{"addr", "metrics_addr", "location.mmdb_path", "drawing"} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("expected a problem with %s, got %v", key, err)
+		}
+	}
+}
+
+func TestProviderNames(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		location  LocationConfig
+		providers string
+	}{
+		{"defaults to ipapi", LocationConfig{}, "ipapi"},
+		{"prefers a local database", LocationConfig{MMDBPath: "city.mmdb"}, "mmdb ipapi"},
+		{"keeps an explicit list", LocationConfig{MMDBPath: "city.mmdb", Providers: []string{"mmdb"}}, "mmdb"},
+	} {
+		if providers := strings.Join(tc.location.ProviderNames(), " "); providers != tc.providers {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.providers, providers)
+		}
+	}
+}
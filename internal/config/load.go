@@ -0,0 +1,149 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a single configurable field of Config.
+type setting struct {
+	key   string
+	usage string
+	value reflect.Value
+}
+
+// settings lists every field of c that can be configured, addressed by its
+// dotted YAML key.
+func settings(c *Config) []setting {
+	var walk func(prefix string, v reflect.Value) []setting
+	walk = func(prefix string, v reflect.Value) []setting {
+		found := []setting{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+			if field.Type.Kind() == reflect.Struct {
+				found = append(found, walk(key+".", v.Field(i))...)
+				continue
+			}
+
+			found = append(found, setting{key: key, usage: field.Tag.Get("usage"), value: v.Field(i)})
+		}
+		return found
+	}
+
+	return walk("", reflect.ValueOf(c).Elem())
+}
+
+var durationType = reflect.TypeOf(Duration(0))
+
+// set parses raw into the setting. Lists are comma separated.
+func (s setting) set(raw string) error {
+	switch {
+	case s.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.key, err)
+		}
+		s.value.SetInt(int64(d))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(raw)
+	case s.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", s.key, raw)
+		}
+		s.value.SetInt(int64(n))
+	case s.value.Kind() == reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		list := reflect.MakeSlice(s.value.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := (setting{key: s.key, value: list.Index(i)}).set(item); err != nil {
+				return err
+			}
+		}
+		s.value.Set(list)
+	default:
+		return fmt.Errorf("%s: unsupported setting type %s", s.key, s.value.Type())
+	}
+
+	return nil
+}
+
+// Invocation is the outcome of parsing the command line.
+type Invocation struct {
+	Config      Config
+	PrintConfig bool
+	// Args are the positional arguments left after flags, such as a
+	// subcommand.
+	Args []string
+}
+
+// Load builds the effective configuration from, in increasing order of
+// precedence, the defaults, the config file named by -config or
+// WEATHER_CONFIG, environment variables and flags.
+func Load(name string, args []string, lookupEnv func(string) (string, bool), output io.Writer) (*Invocation, error) {
+	inv := &Invocation{Config: Default()}
+	all := settings(&inv.Config)
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprintf(output, "usage: %s [flags] [migrate status|up]\n\nflags:\n", name)
+		flags.PrintDefaults()
+	}
+
+	configPath := flags.String("config", "", "path to a YAML config file (env WEATHER_CONFIG)")
+	flags.BoolVar(&inv.PrintConfig, "print-config", false, "print the effective configuration and exit")
+
+	// Flags are applied last, so their values are held until the file and
+	// environment have been read.
+	fromFlags := []func() error{}
+	for _, s := range all {
+		flags.Func(flagName(s.key), fmt.Sprintf("%s (env %s)", s.usage, envName(s.key)), func(raw string) error {
+			fromFlags = append(fromFlags, func() error { return s.set(raw) })
+			return nil
+		})
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	inv.Args = flags.Args()
+
+	if *configPath == "" {
+		*configPath, _ = lookupEnv("WEATHER_CONFIG")
+	}
+	if *configPath != "" {
+		if err := inv.Config.LoadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range all {
+		if raw, ok := lookupEnv(envName(s.key)); ok {
+			if err := s.set(raw); err != nil {
+				return nil, fmt.Errorf("error reading %s: %w", envName(s.key), err)
+			}
+		}
+	}
+
+	for _, set := range fromFlags {
+		if err := set(); err != nil {
+			return nil, err
+		}
+	}
+
+	return inv, nil
+}
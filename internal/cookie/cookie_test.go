@@ -0,0 +1,52 @@
+package cookie
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	type value struct {
+		Lat float64
+		Lon float64
+	}
+
+	signer := NewSigner([]byte("secret"))
+
+	w := httptest.NewRecorder()
+	if err := signer.Set(w, "location", value{Lat: 1.5, Lon: -2.5}, time.Hour); err != nil {
+		t.Fatalf("%v", err)
+	}
+	set := w.Result().Cookies()[0]
+
+	t.Run("reads back signed values", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(set)
+
+		got := value{}
+		if err := signer.Get(r, "location", &got); err != nil || got != (value{1.5, -2.5}) {
+			t.Errorf("expected {1.5 -2.5}, got %+v (%v)", got, err)
+		}
+	})
+
+	t.Run("rejects tampered values", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: "location", Value: "e30" + set.Value[3:]})
+
+		if err := signer.Get(r, "location", &value{}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("rejects values signed with another key", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(set)
+
+		if err := NewSigner([]byte("other")).Get(r, "location", &value{}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+}
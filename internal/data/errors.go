@@ -0,0 +1,47 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Errors that Translate maps SQLite's errors to, so callers can tell what
+// went wrong without knowing the database.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflicts with an existing row")
+	ErrConstraint = errors.New("violates a constraint")
+)
+
+// Translate maps an error from a query to ErrNotFound, ErrConflict or
+// ErrConstraint, still wrapping the original for logging. Errors it doesn't
+// recognize are returned as they are.
+//
+// A failed foreign key is ErrNotFound, since the row being written refers
+// to one that doesn't exist.
+func Translate(err error) error {
+	var sqliteErr sqlite3.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case !errors.As(err, &sqliteErr):
+		return err
+	}
+
+	switch {
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey,
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique:
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case sqliteErr.Code == sqlite3.ErrConstraint:
+		return fmt.Errorf("%w: %w", ErrConstraint, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"weather/internal/database"
+	"weather/internal/migrate"
+
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	db, err := database.Open(":memory:")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := migrate.Load(os.DirFS("../../sqlite/migrations"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := migrate.Up(context.Background(), db.Write, migrations); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return db
+}
+
+func TestTranslate(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := openTestDB(t)
+	db := New(sqlDB)
+
+	obs, err := db.UpsertObservation(ctx, UpsertObservationParams{
+		Timezone:  "UTC",
+		TimeUtc:   time.Now().UTC(),
+		TimeLocal: time.Now().UTC(),
+		BucketUtc: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	drawing := AddObservationDrawingParams{
+		ObservationID: obs.ID,
+		Data:          "drawing",
+		SizeBytes:     7,
+		TimeSubmitted: time.Now().UTC(),
+	}
+
+	t.Run("missing rows are not found", func(t *testing.T) {
+		_, err := db.GetObservation(ctx, obs.ID+1)
+		err = Translate(err)
+		if !errors.Is(err, ErrNotFound) || !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected ErrNotFound wrapping sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("duplicate keys conflict", func(t *testing.T) {
+		if err := Translate(db.AddObservationDrawing(ctx, drawing)); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		err := Translate(db.AddObservationDrawing(ctx, drawing))
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("missing references are not found", func(t *testing.T) {
+		orphan := drawing
+		orphan.ObservationID = obs.ID + 1
+
+		err := Translate(db.AddObservationDrawing(ctx, orphan))
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("other constraints are constraints", func(t *testing.T) {
+		_, err := sqlDB.ExecContext(ctx, "INSERT INTO observation_drawings (observation_id) VALUES (?)", obs.ID+2)
+		err = Translate(err)
+		if !errors.Is(err, ErrConstraint) {
+			t.Errorf("expected ErrConstraint, got %v", err)
+		}
+	})
+
+	t.Run("leaves other errors alone", func(t *testing.T) {
+		other := errors.New("disk on fire")
+		if err := Translate(other); err != other {
+			t.Errorf("expected the same error back, got %v", err)
+		}
+		if err := Translate(nil); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+}
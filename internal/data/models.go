@@ -6,35 +6,88 @@ package data
 
 import (
 	"time"
+
+	"weather/internal/weather"
 )
 
+type Forecast struct {
+	ID            int64     `json:"id"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	Timezone      string    `json:"timezone"`
+	TimeGenerated time.Time `json:"time_generated"`
+	CellLat       int64     `json:"cell_lat"`
+	CellLon       int64     `json:"cell_lon"`
+}
+
+type ForecastDay struct {
+	ForecastID                  int64     `json:"forecast_id"`
+	Date                        string    `json:"date"`
+	TempMinC                    float64   `json:"temp_min_c"`
+	TempMaxC                    float64   `json:"temp_max_c"`
+	PrecipitationProbabilityMax int64     `json:"precipitation_probability_max"`
+	WeatherCode                 int64     `json:"weather_code"`
+	SunriseUtc                  time.Time `json:"sunrise_utc"`
+	SunsetUtc                   time.Time `json:"sunset_utc"`
+}
+
+type ForecastHour struct {
+	ForecastID               int64     `json:"forecast_id"`
+	TimeUtc                  time.Time `json:"time_utc"`
+	TempC                    float64   `json:"temp_c"`
+	PrecipitationProbability int64     `json:"precipitation_probability"`
+	WeatherCode              int64     `json:"weather_code"`
+}
+
 type Geolocation struct {
-	Ip        string
-	Latitude  float64
-	Longitude float64
-	City      string
-	Country   string
-	Timezone  string
+	Ip          string    `json:"ip"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	City        string    `json:"city"`
+	Country     string    `json:"country"`
+	Timezone    string    `json:"timezone"`
+	TimeFetched time.Time `json:"time_fetched"`
 }
 
 type Observation struct {
-	ID               int64
-	Latitude         float64
-	Longitude        float64
-	Timezone         string
-	TempC            float64
-	TempF            float64
-	RelativeHumidity float64
-	Rain             float64
-	Snowfall         float64
-	WeatherCode      string
-	TimeUtc          time.Time
-	TimeLocal        time.Time
+	ID                 int64        `json:"id"`
+	Latitude           float64      `json:"latitude"`
+	Longitude          float64      `json:"longitude"`
+	Timezone           string       `json:"timezone"`
+	TempC              float64      `json:"temp_c"`
+	TempF              float64      `json:"temp_f"`
+	RelativeHumidity   float64      `json:"relative_humidity"`
+	Rain               float64      `json:"rain"`
+	Snowfall           float64      `json:"snowfall"`
+	WeatherCode        weather.Code `json:"weather_code"`
+	TimeUtc            time.Time    `json:"time_utc"`
+	TimeLocal          time.Time    `json:"time_local"`
+	Country            string       `json:"country"`
+	ApparentTempC      *float64     `json:"apparent_temp_c"`
+	Precipitation      *float64     `json:"precipitation"`
+	WindSpeedKmh       *float64     `json:"wind_speed_kmh"`
+	WindDirection      *float64     `json:"wind_direction"`
+	WindGustsKmh       *float64     `json:"wind_gusts_kmh"`
+	SurfacePressureHpa *float64     `json:"surface_pressure_hpa"`
+	CloudCover         *float64     `json:"cloud_cover"`
+	UvIndex            *float64     `json:"uv_index"`
+	IsDay              *bool        `json:"is_day"`
+	CellLat            int64        `json:"cell_lat"`
+	CellLon            int64        `json:"cell_lon"`
+	BucketUtc          time.Time    `json:"bucket_utc"`
+	WeatherCategory    string       `json:"weather_category"`
 }
 
 type ObservationDrawing struct {
-	ObservationID int64
-	Data          string
-	SizeBytes     int64
-	TimeSubmitted time.Time
+	ObservationID int64     `json:"observation_id"`
+	Data          string    `json:"data"`
+	SizeBytes     int64     `json:"size_bytes"`
+	TimeSubmitted time.Time `json:"time_submitted"`
+}
+
+type ObservationDrawingImage struct {
+	ObservationID int64     `json:"observation_id"`
+	Palette       string    `json:"palette"`
+	Png           []byte    `json:"png"`
+	TimeRendered  time.Time `json:"time_rendered"`
 }
@@ -7,113 +7,130 @@ package data
 
 import (
 	"context"
+	"database/sql"
 	"time"
+
+	"weather/internal/weather"
 )
 
-const addGeolocation = `-- name: AddGeolocation :one
+const addForecast = `-- name: AddForecast :one
 INSERT INTO
-    geolocations (ip, latitude, longitude, city, country, timezone)
+    forecasts (
+        latitude,
+        longitude,
+        cell_lat,
+        cell_lon,
+        timezone,
+        time_generated
+    )
 VALUES
     (?, ?, ?, ?, ?, ?)
 RETURNING
-    ip, latitude, longitude, city, country, timezone
+    id, latitude, longitude, timezone, time_generated, cell_lat, cell_lon
 `
 
-type AddGeolocationParams struct {
-	Ip        string
-	Latitude  float64
-	Longitude float64
-	City      string
-	Country   string
-	Timezone  string
+type AddForecastParams struct {
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	CellLat       int64     `json:"cell_lat"`
+	CellLon       int64     `json:"cell_lon"`
+	Timezone      string    `json:"timezone"`
+	TimeGenerated time.Time `json:"time_generated"`
 }
 
-func (q *Queries) AddGeolocation(ctx context.Context, arg AddGeolocationParams) (Geolocation, error) {
-	row := q.db.QueryRowContext(ctx, addGeolocation,
-		arg.Ip,
+func (q *Queries) AddForecast(ctx context.Context, arg AddForecastParams) (Forecast, error) {
+	row := q.db.QueryRowContext(ctx, addForecast,
 		arg.Latitude,
 		arg.Longitude,
-		arg.City,
-		arg.Country,
+		arg.CellLat,
+		arg.CellLon,
 		arg.Timezone,
+		arg.TimeGenerated,
 	)
-	var i Geolocation
+	var i Forecast
 	err := row.Scan(
-		&i.Ip,
+		&i.ID,
 		&i.Latitude,
 		&i.Longitude,
-		&i.City,
-		&i.Country,
 		&i.Timezone,
+		&i.TimeGenerated,
+		&i.CellLat,
+		&i.CellLon,
 	)
 	return i, err
 }
 
-const addObservation = `-- name: AddObservation :one
+const addForecastDay = `-- name: AddForecastDay :exec
 INSERT INTO
-    observations (
-        latitude,
-        longitude,
-        timezone,
-        temp_c,
-        temp_f,
-        relative_humidity,
-        rain,
-        snowfall,
+    forecast_days (
+        forecast_id,
+        date,
+        temp_min_c,
+        temp_max_c,
+        precipitation_probability_max,
         weather_code,
+        sunrise_utc,
+        sunset_utc
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type AddForecastDayParams struct {
+	ForecastID                  int64     `json:"forecast_id"`
+	Date                        string    `json:"date"`
+	TempMinC                    float64   `json:"temp_min_c"`
+	TempMaxC                    float64   `json:"temp_max_c"`
+	PrecipitationProbabilityMax int64     `json:"precipitation_probability_max"`
+	WeatherCode                 int64     `json:"weather_code"`
+	SunriseUtc                  time.Time `json:"sunrise_utc"`
+	SunsetUtc                   time.Time `json:"sunset_utc"`
+}
+
+func (q *Queries) AddForecastDay(ctx context.Context, arg AddForecastDayParams) error {
+	_, err := q.db.ExecContext(ctx, addForecastDay,
+		arg.ForecastID,
+		arg.Date,
+		arg.TempMinC,
+		arg.TempMaxC,
+		arg.PrecipitationProbabilityMax,
+		arg.WeatherCode,
+		arg.SunriseUtc,
+		arg.SunsetUtc,
+	)
+	return err
+}
+
+const addForecastHour = `-- name: AddForecastHour :exec
+INSERT INTO
+    forecast_hours (
+        forecast_id,
         time_utc,
-        time_local
+        temp_c,
+        precipitation_probability,
+        weather_code
     )
 VALUES
-    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-RETURNING
-    id, latitude, longitude, timezone, temp_c, temp_f, relative_humidity, rain, snowfall, weather_code, time_utc, time_local
+    (?, ?, ?, ?, ?)
 `
 
-type AddObservationParams struct {
-	Latitude         float64
-	Longitude        float64
-	Timezone         string
-	TempC            float64
-	TempF            float64
-	RelativeHumidity float64
-	Rain             float64
-	Snowfall         float64
-	WeatherCode      string
-	TimeUtc          time.Time
-	TimeLocal        time.Time
+type AddForecastHourParams struct {
+	ForecastID               int64     `json:"forecast_id"`
+	TimeUtc                  time.Time `json:"time_utc"`
+	TempC                    float64   `json:"temp_c"`
+	PrecipitationProbability int64     `json:"precipitation_probability"`
+	WeatherCode              int64     `json:"weather_code"`
 }
 
-func (q *Queries) AddObservation(ctx context.Context, arg AddObservationParams) (Observation, error) {
-	row := q.db.QueryRowContext(ctx, addObservation,
-		arg.Latitude,
-		arg.Longitude,
-		arg.Timezone,
+func (q *Queries) AddForecastHour(ctx context.Context, arg AddForecastHourParams) error {
+	_, err := q.db.ExecContext(ctx, addForecastHour,
+		arg.ForecastID,
+		arg.TimeUtc,
 		arg.TempC,
-		arg.TempF,
-		arg.RelativeHumidity,
-		arg.Rain,
-		arg.Snowfall,
+		arg.PrecipitationProbability,
 		arg.WeatherCode,
-		arg.TimeUtc,
-		arg.TimeLocal,
-	)
-	var i Observation
-	err := row.Scan(
-		&i.ID,
-		&i.Latitude,
-		&i.Longitude,
-		&i.Timezone,
-		&i.TempC,
-		&i.TempF,
-		&i.RelativeHumidity,
-		&i.Rain,
-		&i.Snowfall,
-		&i.WeatherCode,
-		&i.TimeUtc,
-		&i.TimeLocal,
 	)
-	return i, err
+	return err
 }
 
 const addObservationDrawing = `-- name: AddObservationDrawing :exec
@@ -126,10 +143,10 @@ RETURNING
 `
 
 type AddObservationDrawingParams struct {
-	ObservationID int64
-	Data          string
-	SizeBytes     int64
-	TimeSubmitted time.Time
+	ObservationID int64     `json:"observation_id"`
+	Data          string    `json:"data"`
+	SizeBytes     int64     `json:"size_bytes"`
+	TimeSubmitted time.Time `json:"time_submitted"`
 }
 
 func (q *Queries) AddObservationDrawing(ctx context.Context, arg AddObservationDrawingParams) error {
@@ -142,9 +159,112 @@ func (q *Queries) AddObservationDrawing(ctx context.Context, arg AddObservationD
 	return err
 }
 
+const addObservationDrawingImage = `-- name: AddObservationDrawingImage :exec
+INSERT OR REPLACE INTO
+    observation_drawing_images (observation_id, palette, png, time_rendered)
+VALUES
+    (?, ?, ?, ?)
+`
+
+type AddObservationDrawingImageParams struct {
+	ObservationID int64     `json:"observation_id"`
+	Palette       string    `json:"palette"`
+	Png           []byte    `json:"png"`
+	TimeRendered  time.Time `json:"time_rendered"`
+}
+
+func (q *Queries) AddObservationDrawingImage(ctx context.Context, arg AddObservationDrawingImageParams) error {
+	_, err := q.db.ExecContext(ctx, addObservationDrawingImage,
+		arg.ObservationID,
+		arg.Palette,
+		arg.Png,
+		arg.TimeRendered,
+	)
+	return err
+}
+
+const countDrawnObservationsByCategory = `-- name: CountDrawnObservationsByCategory :many
+SELECT
+    o.weather_category,
+    COUNT(*) AS observations
+FROM
+    observations o
+    INNER JOIN observation_drawings od ON o.id = od.observation_id
+WHERE
+    o.weather_category != ''
+GROUP BY
+    o.weather_category
+`
+
+type CountDrawnObservationsByCategoryRow struct {
+	WeatherCategory string `json:"weather_category"`
+	Observations    int64  `json:"observations"`
+}
+
+func (q *Queries) CountDrawnObservationsByCategory(ctx context.Context) ([]CountDrawnObservationsByCategoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, countDrawnObservationsByCategory)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountDrawnObservationsByCategoryRow
+	for rows.Next() {
+		var i CountDrawnObservationsByCategoryRow
+		if err := rows.Scan(&i.WeatherCategory, &i.Observations); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteStaleObservationDrawingImages = `-- name: DeleteStaleObservationDrawingImages :exec
+DELETE FROM
+    observation_drawing_images
+WHERE
+    observation_id = ?
+    AND palette != ?
+`
+
+type DeleteStaleObservationDrawingImagesParams struct {
+	ObservationID int64  `json:"observation_id"`
+	Palette       string `json:"palette"`
+}
+
+func (q *Queries) DeleteStaleObservationDrawingImages(ctx context.Context, arg DeleteStaleObservationDrawingImagesParams) error {
+	_, err := q.db.ExecContext(ctx, deleteStaleObservationDrawingImages, arg.ObservationID, arg.Palette)
+	return err
+}
+
+const deleteSupersededForecasts = `-- name: DeleteSupersededForecasts :exec
+DELETE FROM
+    forecasts
+WHERE
+    cell_lat = ?
+    AND cell_lon = ?
+    AND id != ?
+`
+
+type DeleteSupersededForecastsParams struct {
+	CellLat int64 `json:"cell_lat"`
+	CellLon int64 `json:"cell_lon"`
+	ID      int64 `json:"id"`
+}
+
+func (q *Queries) DeleteSupersededForecasts(ctx context.Context, arg DeleteSupersededForecastsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteSupersededForecasts, arg.CellLat, arg.CellLon, arg.ID)
+	return err
+}
+
 const getGeolocation = `-- name: GetGeolocation :one
 SELECT
-    ip, latitude, longitude, city, country, timezone
+    ip, latitude, longitude, city, country, timezone, time_fetched
 FROM
     geolocations
 WHERE
@@ -161,13 +281,14 @@ func (q *Queries) GetGeolocation(ctx context.Context, ip string) (Geolocation, e
 		&i.City,
 		&i.Country,
 		&i.Timezone,
+		&i.TimeFetched,
 	)
 	return i, err
 }
 
 const getObservation = `-- name: GetObservation :one
 SELECT
-    id, latitude, longitude, timezone, temp_c, temp_f, relative_humidity, rain, snowfall, weather_code, time_utc, time_local
+    id, latitude, longitude, timezone, temp_c, temp_f, relative_humidity, rain, snowfall, weather_code, time_utc, time_local, country, apparent_temp_c, precipitation, wind_speed_kmh, wind_direction, wind_gusts_kmh, surface_pressure_hpa, cloud_cover, uv_index, is_day, cell_lat, cell_lon, bucket_utc, weather_category
 FROM
     observations
 WHERE
@@ -190,41 +311,733 @@ func (q *Queries) GetObservation(ctx context.Context, id int64) (Observation, er
 		&i.WeatherCode,
 		&i.TimeUtc,
 		&i.TimeLocal,
+		&i.Country,
+		&i.ApparentTempC,
+		&i.Precipitation,
+		&i.WindSpeedKmh,
+		&i.WindDirection,
+		&i.WindGustsKmh,
+		&i.SurfacePressureHpa,
+		&i.CloudCover,
+		&i.UvIndex,
+		&i.IsDay,
+		&i.CellLat,
+		&i.CellLon,
+		&i.BucketUtc,
+		&i.WeatherCategory,
+	)
+	return i, err
+}
+
+const getObservationDrawing = `-- name: GetObservationDrawing :one
+SELECT
+    observation_id, data, size_bytes, time_submitted
+FROM
+    observation_drawings
+WHERE
+    observation_id = ?
+`
+
+func (q *Queries) GetObservationDrawing(ctx context.Context, observationID int64) (ObservationDrawing, error) {
+	row := q.db.QueryRowContext(ctx, getObservationDrawing, observationID)
+	var i ObservationDrawing
+	err := row.Scan(
+		&i.ObservationID,
+		&i.Data,
+		&i.SizeBytes,
+		&i.TimeSubmitted,
+	)
+	return i, err
+}
+
+const getObservationDrawingImage = `-- name: GetObservationDrawingImage :one
+SELECT
+    observation_id, palette, png, time_rendered
+FROM
+    observation_drawing_images
+WHERE
+    observation_id = ?
+    AND palette = ?
+`
+
+type GetObservationDrawingImageParams struct {
+	ObservationID int64  `json:"observation_id"`
+	Palette       string `json:"palette"`
+}
+
+func (q *Queries) GetObservationDrawingImage(ctx context.Context, arg GetObservationDrawingImageParams) (ObservationDrawingImage, error) {
+	row := q.db.QueryRowContext(ctx, getObservationDrawingImage, arg.ObservationID, arg.Palette)
+	var i ObservationDrawingImage
+	err := row.Scan(
+		&i.ObservationID,
+		&i.Palette,
+		&i.Png,
+		&i.TimeRendered,
+	)
+	return i, err
+}
+
+const getObservationInCell = `-- name: GetObservationInCell :one
+SELECT
+    id, latitude, longitude, timezone, temp_c, temp_f, relative_humidity, rain, snowfall, weather_code, time_utc, time_local, country, apparent_temp_c, precipitation, wind_speed_kmh, wind_direction, wind_gusts_kmh, surface_pressure_hpa, cloud_cover, uv_index, is_day, cell_lat, cell_lon, bucket_utc, weather_category
+FROM
+    observations
+WHERE
+    cell_lat = ?
+    AND cell_lon = ?
+    AND bucket_utc = ?
+`
+
+type GetObservationInCellParams struct {
+	CellLat   int64     `json:"cell_lat"`
+	CellLon   int64     `json:"cell_lon"`
+	BucketUtc time.Time `json:"bucket_utc"`
+}
+
+func (q *Queries) GetObservationInCell(ctx context.Context, arg GetObservationInCellParams) (Observation, error) {
+	row := q.db.QueryRowContext(ctx, getObservationInCell, arg.CellLat, arg.CellLon, arg.BucketUtc)
+	var i Observation
+	err := row.Scan(
+		&i.ID,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Timezone,
+		&i.TempC,
+		&i.TempF,
+		&i.RelativeHumidity,
+		&i.Rain,
+		&i.Snowfall,
+		&i.WeatherCode,
+		&i.TimeUtc,
+		&i.TimeLocal,
+		&i.Country,
+		&i.ApparentTempC,
+		&i.Precipitation,
+		&i.WindSpeedKmh,
+		&i.WindDirection,
+		&i.WindGustsKmh,
+		&i.SurfacePressureHpa,
+		&i.CloudCover,
+		&i.UvIndex,
+		&i.IsDay,
+		&i.CellLat,
+		&i.CellLon,
+		&i.BucketUtc,
+		&i.WeatherCategory,
 	)
 	return i, err
 }
 
-const priorObservation = `-- name: PriorObservation :one
+const getRecentForecast = `-- name: GetRecentForecast :one
 SELECT
-    id, latitude, longitude, timezone, temp_c, temp_f, relative_humidity, rain, snowfall, weather_code, time_utc, time_local, observation_id, data, size_bytes, time_submitted
+    id, latitude, longitude, timezone, time_generated, cell_lat, cell_lon
+FROM
+    forecasts
+WHERE
+    cell_lat = ?
+    AND cell_lon = ?
+    AND time_generated >= ?
+ORDER BY
+    time_generated DESC
+LIMIT
+    1
+`
+
+type GetRecentForecastParams struct {
+	CellLat       int64     `json:"cell_lat"`
+	CellLon       int64     `json:"cell_lon"`
+	TimeGenerated time.Time `json:"time_generated"`
+}
+
+func (q *Queries) GetRecentForecast(ctx context.Context, arg GetRecentForecastParams) (Forecast, error) {
+	row := q.db.QueryRowContext(ctx, getRecentForecast, arg.CellLat, arg.CellLon, arg.TimeGenerated)
+	var i Forecast
+	err := row.Scan(
+		&i.ID,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Timezone,
+		&i.TimeGenerated,
+		&i.CellLat,
+		&i.CellLon,
+	)
+	return i, err
+}
+
+const hasObservationInCell = `-- name: HasObservationInCell :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            observations
+        WHERE
+            cell_lat = ?
+            AND cell_lon = ?
+    ) AS observed
+`
+
+type HasObservationInCellParams struct {
+	CellLat int64 `json:"cell_lat"`
+	CellLon int64 `json:"cell_lon"`
+}
+
+func (q *Queries) HasObservationInCell(ctx context.Context, arg HasObservationInCellParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, hasObservationInCell, arg.CellLat, arg.CellLon)
+	var observed int64
+	err := row.Scan(&observed)
+	return observed, err
+}
+
+const listDrawnObservations = `-- name: ListDrawnObservations :many
+SELECT
+    o.id, o.latitude, o.longitude, o.timezone, o.temp_c, o.temp_f, o.relative_humidity, o.rain, o.snowfall, o.weather_code, o.time_utc, o.time_local, o.country, o.apparent_temp_c, o.precipitation, o.wind_speed_kmh, o.wind_direction, o.wind_gusts_kmh, o.surface_pressure_hpa, o.cloud_cover, o.uv_index, o.is_day, o.cell_lat, o.cell_lon, o.bucket_utc, o.weather_category,
+    od.observation_id, od.data, od.size_bytes, od.time_submitted
 FROM
     observations o
     INNER JOIN observation_drawings od ON o.id = od.observation_id
+WHERE
+    (?1 IS NULL OR o.id < ?1)
+    AND (?2 IS NULL OR o.weather_category = ?2)
+    AND (?3 IS NULL OR o.temp_c >= ?3)
+    AND (?4 IS NULL OR o.temp_c <= ?4)
+    AND (?5 IS NULL OR o.country = ?5 COLLATE NOCASE)
+    AND (?6 IS NULL OR o.time_utc >= ?6)
+    AND (?7 IS NULL OR o.time_utc < ?7)
+ORDER BY
+    o.id DESC
+LIMIT
+    ?8
 `
 
-type PriorObservationRow struct {
-	ID               int64
-	Latitude         float64
-	Longitude        float64
-	Timezone         string
-	TempC            float64
-	TempF            float64
-	RelativeHumidity float64
-	Rain             float64
-	Snowfall         float64
-	WeatherCode      string
-	TimeUtc          time.Time
-	TimeLocal        time.Time
-	ObservationID    int64
-	Data             string
-	SizeBytes        int64
-	TimeSubmitted    time.Time
-}
-
-// todo - returns best
-func (q *Queries) PriorObservation(ctx context.Context) (PriorObservationRow, error) {
-	row := q.db.QueryRowContext(ctx, priorObservation)
-	var i PriorObservationRow
+type ListDrawnObservationsParams struct {
+	BeforeID        sql.NullInt64   `json:"before_id"`
+	WeatherCategory sql.NullString  `json:"weather_category"`
+	MinTempC        sql.NullFloat64 `json:"min_temp_c"`
+	MaxTempC        sql.NullFloat64 `json:"max_temp_c"`
+	Country         sql.NullString  `json:"country"`
+	Since           sql.NullTime    `json:"since"`
+	Until           sql.NullTime    `json:"until"`
+	Limit           int64           `json:"limit"`
+}
+
+type ListDrawnObservationsRow struct {
+	Observation        Observation        `json:"observation"`
+	ObservationDrawing ObservationDrawing `json:"observation_drawing"`
+}
+
+func (q *Queries) ListDrawnObservations(ctx context.Context, arg ListDrawnObservationsParams) ([]ListDrawnObservationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDrawnObservations,
+		arg.BeforeID,
+		arg.WeatherCategory,
+		arg.MinTempC,
+		arg.MaxTempC,
+		arg.Country,
+		arg.Since,
+		arg.Until,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDrawnObservationsRow
+	for rows.Next() {
+		var i ListDrawnObservationsRow
+		if err := rows.Scan(
+			&i.Observation.ID,
+			&i.Observation.Latitude,
+			&i.Observation.Longitude,
+			&i.Observation.Timezone,
+			&i.Observation.TempC,
+			&i.Observation.TempF,
+			&i.Observation.RelativeHumidity,
+			&i.Observation.Rain,
+			&i.Observation.Snowfall,
+			&i.Observation.WeatherCode,
+			&i.Observation.TimeUtc,
+			&i.Observation.TimeLocal,
+			&i.Observation.Country,
+			&i.Observation.ApparentTempC,
+			&i.Observation.Precipitation,
+			&i.Observation.WindSpeedKmh,
+			&i.Observation.WindDirection,
+			&i.Observation.WindGustsKmh,
+			&i.Observation.SurfacePressureHpa,
+			&i.Observation.CloudCover,
+			&i.Observation.UvIndex,
+			&i.Observation.IsDay,
+			&i.Observation.CellLat,
+			&i.Observation.CellLon,
+			&i.Observation.BucketUtc,
+			&i.Observation.WeatherCategory,
+			&i.ObservationDrawing.ObservationID,
+			&i.ObservationDrawing.Data,
+			&i.ObservationDrawing.SizeBytes,
+			&i.ObservationDrawing.TimeSubmitted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listForecastDays = `-- name: ListForecastDays :many
+SELECT
+    forecast_id, date, temp_min_c, temp_max_c, precipitation_probability_max, weather_code, sunrise_utc, sunset_utc
+FROM
+    forecast_days
+WHERE
+    forecast_id = ?
+ORDER BY
+    date
+`
+
+func (q *Queries) ListForecastDays(ctx context.Context, forecastID int64) ([]ForecastDay, error) {
+	rows, err := q.db.QueryContext(ctx, listForecastDays, forecastID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ForecastDay
+	for rows.Next() {
+		var i ForecastDay
+		if err := rows.Scan(
+			&i.ForecastID,
+			&i.Date,
+			&i.TempMinC,
+			&i.TempMaxC,
+			&i.PrecipitationProbabilityMax,
+			&i.WeatherCode,
+			&i.SunriseUtc,
+			&i.SunsetUtc,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listForecastHours = `-- name: ListForecastHours :many
+SELECT
+    forecast_id, time_utc, temp_c, precipitation_probability, weather_code
+FROM
+    forecast_hours
+WHERE
+    forecast_id = ?
+ORDER BY
+    time_utc
+`
+
+func (q *Queries) ListForecastHours(ctx context.Context, forecastID int64) ([]ForecastHour, error) {
+	rows, err := q.db.QueryContext(ctx, listForecastHours, forecastID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ForecastHour
+	for rows.Next() {
+		var i ForecastHour
+		if err := rows.Scan(
+			&i.ForecastID,
+			&i.TimeUtc,
+			&i.TempC,
+			&i.PrecipitationProbability,
+			&i.WeatherCode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listObservationCountries = `-- name: ListObservationCountries :many
+SELECT DISTINCT
+    country
+FROM
+    observations
+WHERE
+    country != ''
+ORDER BY
+    country
+`
+
+func (q *Queries) ListObservationCountries(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listObservationCountries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var country string
+		if err := rows.Scan(&country); err != nil {
+			return nil, err
+		}
+		items = append(items, country)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listObservations = `-- name: ListObservations :many
+SELECT
+    o.id, o.latitude, o.longitude, o.timezone, o.temp_c, o.temp_f, o.relative_humidity, o.rain, o.snowfall, o.weather_code, o.time_utc, o.time_local, o.country, o.apparent_temp_c, o.precipitation, o.wind_speed_kmh, o.wind_direction, o.wind_gusts_kmh, o.surface_pressure_hpa, o.cloud_cover, o.uv_index, o.is_day, o.cell_lat, o.cell_lon, o.bucket_utc, o.weather_category,
+    CAST(od.observation_id IS NOT NULL AS BOOLEAN) AS has_drawing
+FROM
+    observations o
+    LEFT JOIN observation_drawings od ON o.id = od.observation_id
+WHERE
+    (?1 IS NULL OR o.id < ?1)
+    AND (?2 IS NULL OR o.weather_code = ?2)
+    AND (?3 IS NULL OR o.temp_c >= ?3)
+    AND (?4 IS NULL OR o.temp_c <= ?4)
+    AND (?5 IS NULL OR o.time_utc >= ?5)
+    AND (?6 IS NULL OR o.time_utc < ?6)
+    AND (
+        CAST(?7 AS BOOLEAN) IS NULL
+        OR (od.observation_id IS NOT NULL) = CAST(?7 AS BOOLEAN)
+    )
+ORDER BY
+    o.id DESC
+LIMIT
+    ?8
+`
+
+type ListObservationsParams struct {
+	BeforeID    sql.NullInt64   `json:"before_id"`
+	WeatherCode sql.NullString  `json:"weather_code"`
+	MinTempC    sql.NullFloat64 `json:"min_temp_c"`
+	MaxTempC    sql.NullFloat64 `json:"max_temp_c"`
+	Since       sql.NullTime    `json:"since"`
+	Until       sql.NullTime    `json:"until"`
+	HasDrawing  sql.NullBool    `json:"has_drawing"`
+	Limit       int64           `json:"limit"`
+}
+
+type ListObservationsRow struct {
+	Observation Observation `json:"observation"`
+	HasDrawing  bool        `json:"has_drawing"`
+}
+
+func (q *Queries) ListObservations(ctx context.Context, arg ListObservationsParams) ([]ListObservationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listObservations,
+		arg.BeforeID,
+		arg.WeatherCode,
+		arg.MinTempC,
+		arg.MaxTempC,
+		arg.Since,
+		arg.Until,
+		arg.HasDrawing,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListObservationsRow
+	for rows.Next() {
+		var i ListObservationsRow
+		if err := rows.Scan(
+			&i.Observation.ID,
+			&i.Observation.Latitude,
+			&i.Observation.Longitude,
+			&i.Observation.Timezone,
+			&i.Observation.TempC,
+			&i.Observation.TempF,
+			&i.Observation.RelativeHumidity,
+			&i.Observation.Rain,
+			&i.Observation.Snowfall,
+			&i.Observation.WeatherCode,
+			&i.Observation.TimeUtc,
+			&i.Observation.TimeLocal,
+			&i.Observation.Country,
+			&i.Observation.ApparentTempC,
+			&i.Observation.Precipitation,
+			&i.Observation.WindSpeedKmh,
+			&i.Observation.WindDirection,
+			&i.Observation.WindGustsKmh,
+			&i.Observation.SurfacePressureHpa,
+			&i.Observation.CloudCover,
+			&i.Observation.UvIndex,
+			&i.Observation.IsDay,
+			&i.Observation.CellLat,
+			&i.Observation.CellLon,
+			&i.Observation.BucketUtc,
+			&i.Observation.WeatherCategory,
+			&i.HasDrawing,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const priorObservationCandidates = `-- name: PriorObservationCandidates :many
+SELECT
+    o.id, o.latitude, o.longitude, o.timezone, o.temp_c, o.temp_f, o.relative_humidity, o.rain, o.snowfall, o.weather_code, o.time_utc, o.time_local, o.country, o.apparent_temp_c, o.precipitation, o.wind_speed_kmh, o.wind_direction, o.wind_gusts_kmh, o.surface_pressure_hpa, o.cloud_cover, o.uv_index, o.is_day, o.cell_lat, o.cell_lon, o.bucket_utc, o.weather_category,
+    od.observation_id, od.data, od.size_bytes, od.time_submitted
+FROM
+    observations o
+    INNER JOIN observation_drawings od ON o.id = od.observation_id
+WHERE
+    o.id != ?
+ORDER BY
+    o.time_utc DESC
+LIMIT
+    ?
+`
+
+type PriorObservationCandidatesParams struct {
+	ID    int64 `json:"id"`
+	Limit int64 `json:"limit"`
+}
+
+type PriorObservationCandidatesRow struct {
+	Observation        Observation        `json:"observation"`
+	ObservationDrawing ObservationDrawing `json:"observation_drawing"`
+}
+
+func (q *Queries) PriorObservationCandidates(ctx context.Context, arg PriorObservationCandidatesParams) ([]PriorObservationCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, priorObservationCandidates, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PriorObservationCandidatesRow
+	for rows.Next() {
+		var i PriorObservationCandidatesRow
+		if err := rows.Scan(
+			&i.Observation.ID,
+			&i.Observation.Latitude,
+			&i.Observation.Longitude,
+			&i.Observation.Timezone,
+			&i.Observation.TempC,
+			&i.Observation.TempF,
+			&i.Observation.RelativeHumidity,
+			&i.Observation.Rain,
+			&i.Observation.Snowfall,
+			&i.Observation.WeatherCode,
+			&i.Observation.TimeUtc,
+			&i.Observation.TimeLocal,
+			&i.Observation.Country,
+			&i.Observation.ApparentTempC,
+			&i.Observation.Precipitation,
+			&i.Observation.WindSpeedKmh,
+			&i.Observation.WindDirection,
+			&i.Observation.WindGustsKmh,
+			&i.Observation.SurfacePressureHpa,
+			&i.Observation.CloudCover,
+			&i.Observation.UvIndex,
+			&i.Observation.IsDay,
+			&i.Observation.CellLat,
+			&i.Observation.CellLon,
+			&i.Observation.BucketUtc,
+			&i.Observation.WeatherCategory,
+			&i.ObservationDrawing.ObservationID,
+			&i.ObservationDrawing.Data,
+			&i.ObservationDrawing.SizeBytes,
+			&i.ObservationDrawing.TimeSubmitted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertGeolocation = `-- name: UpsertGeolocation :one
+INSERT INTO
+    geolocations (
+        ip,
+        latitude,
+        longitude,
+        city,
+        country,
+        timezone,
+        time_fetched
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (ip) DO UPDATE
+SET
+    latitude = excluded.latitude,
+    longitude = excluded.longitude,
+    city = excluded.city,
+    country = excluded.country,
+    timezone = excluded.timezone,
+    time_fetched = excluded.time_fetched
+RETURNING
+    ip, latitude, longitude, city, country, timezone, time_fetched
+`
+
+type UpsertGeolocationParams struct {
+	Ip          string    `json:"ip"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	City        string    `json:"city"`
+	Country     string    `json:"country"`
+	Timezone    string    `json:"timezone"`
+	TimeFetched time.Time `json:"time_fetched"`
+}
+
+func (q *Queries) UpsertGeolocation(ctx context.Context, arg UpsertGeolocationParams) (Geolocation, error) {
+	row := q.db.QueryRowContext(ctx, upsertGeolocation,
+		arg.Ip,
+		arg.Latitude,
+		arg.Longitude,
+		arg.City,
+		arg.Country,
+		arg.Timezone,
+		arg.TimeFetched,
+	)
+	var i Geolocation
+	err := row.Scan(
+		&i.Ip,
+		&i.Latitude,
+		&i.Longitude,
+		&i.City,
+		&i.Country,
+		&i.Timezone,
+		&i.TimeFetched,
+	)
+	return i, err
+}
+
+const upsertObservation = `-- name: UpsertObservation :one
+INSERT INTO
+    observations (
+        latitude,
+        longitude,
+        timezone,
+        temp_c,
+        temp_f,
+        relative_humidity,
+        rain,
+        snowfall,
+        weather_code,
+        time_utc,
+        time_local,
+        country,
+        apparent_temp_c,
+        precipitation,
+        wind_speed_kmh,
+        wind_direction,
+        wind_gusts_kmh,
+        surface_pressure_hpa,
+        cloud_cover,
+        uv_index,
+        is_day,
+        cell_lat,
+        cell_lon,
+        bucket_utc,
+        weather_category
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (cell_lat, cell_lon, bucket_utc) DO UPDATE
+SET
+    cell_lat = excluded.cell_lat
+RETURNING
+    id, latitude, longitude, timezone, temp_c, temp_f, relative_humidity, rain, snowfall, weather_code, time_utc, time_local, country, apparent_temp_c, precipitation, wind_speed_kmh, wind_direction, wind_gusts_kmh, surface_pressure_hpa, cloud_cover, uv_index, is_day, cell_lat, cell_lon, bucket_utc, weather_category
+`
+
+type UpsertObservationParams struct {
+	Latitude           float64      `json:"latitude"`
+	Longitude          float64      `json:"longitude"`
+	Timezone           string       `json:"timezone"`
+	TempC              float64      `json:"temp_c"`
+	TempF              float64      `json:"temp_f"`
+	RelativeHumidity   float64      `json:"relative_humidity"`
+	Rain               float64      `json:"rain"`
+	Snowfall           float64      `json:"snowfall"`
+	WeatherCode        weather.Code `json:"weather_code"`
+	TimeUtc            time.Time    `json:"time_utc"`
+	TimeLocal          time.Time    `json:"time_local"`
+	Country            string       `json:"country"`
+	ApparentTempC      *float64     `json:"apparent_temp_c"`
+	Precipitation      *float64     `json:"precipitation"`
+	WindSpeedKmh       *float64     `json:"wind_speed_kmh"`
+	WindDirection      *float64     `json:"wind_direction"`
+	WindGustsKmh       *float64     `json:"wind_gusts_kmh"`
+	SurfacePressureHpa *float64     `json:"surface_pressure_hpa"`
+	CloudCover         *float64     `json:"cloud_cover"`
+	UvIndex            *float64     `json:"uv_index"`
+	IsDay              *bool        `json:"is_day"`
+	CellLat            int64        `json:"cell_lat"`
+	CellLon            int64        `json:"cell_lon"`
+	BucketUtc          time.Time    `json:"bucket_utc"`
+	WeatherCategory    string       `json:"weather_category"`
+}
+
+func (q *Queries) UpsertObservation(ctx context.Context, arg UpsertObservationParams) (Observation, error) {
+	row := q.db.QueryRowContext(ctx, upsertObservation,
+		arg.Latitude,
+		arg.Longitude,
+		arg.Timezone,
+		arg.TempC,
+		arg.TempF,
+		arg.RelativeHumidity,
+		arg.Rain,
+		arg.Snowfall,
+		arg.WeatherCode,
+		arg.TimeUtc,
+		arg.TimeLocal,
+		arg.Country,
+		arg.ApparentTempC,
+		arg.Precipitation,
+		arg.WindSpeedKmh,
+		arg.WindDirection,
+		arg.WindGustsKmh,
+		arg.SurfacePressureHpa,
+		arg.CloudCover,
+		arg.UvIndex,
+		arg.IsDay,
+		arg.CellLat,
+		arg.CellLon,
+		arg.BucketUtc,
+		arg.WeatherCategory,
+	)
+	var i Observation
 	err := row.Scan(
 		&i.ID,
 		&i.Latitude,
@@ -238,10 +1051,20 @@ func (q *Queries) PriorObservation(ctx context.Context) (PriorObservationRow, er
 		&i.WeatherCode,
 		&i.TimeUtc,
 		&i.TimeLocal,
-		&i.ObservationID,
-		&i.Data,
-		&i.SizeBytes,
-		&i.TimeSubmitted,
+		&i.Country,
+		&i.ApparentTempC,
+		&i.Precipitation,
+		&i.WindSpeedKmh,
+		&i.WindDirection,
+		&i.WindGustsKmh,
+		&i.SurfacePressureHpa,
+		&i.CloudCover,
+		&i.UvIndex,
+		&i.IsDay,
+		&i.CellLat,
+		&i.CellLon,
+		&i.BucketUtc,
+		&i.WeatherCategory,
 	)
 	return i, err
 }
@@ -0,0 +1,139 @@
+// Package database opens the SQLite database the app stores everything in.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// BusyTimeout is how long a connection waits for another to release a lock
+// before failing with "database is locked".
+const BusyTimeout = 5 * time.Second
+
+// memory is the path SQLite reads as a private in-memory database.
+const memory = ":memory:"
+
+// DB is a SQLite database opened as two pools. Reads share several
+// connections, which WAL lets run alongside a write, while writes share one
+// so they queue in Go rather than contending for SQLite's lock.
+//
+// DB routes the queries in package data to the right pool, so it can be
+// passed to data.New.
+type DB struct {
+	Read  *sql.DB
+	Write *sql.DB
+}
+
+// DSN is the data source name for the database at path, with the pragmas
+// every connection needs. Writers take their lock when a transaction
+// begins, rather than failing to upgrade a read lock halfway through.
+func DSN(path string, readOnly bool) string {
+	params := url.Values{}
+	params.Set("_journal_mode", "WAL")
+	params.Set("_synchronous", "NORMAL")
+	params.Set("_busy_timeout", fmt.Sprint(BusyTimeout.Milliseconds()))
+	params.Set("_foreign_keys", "on")
+	if readOnly {
+		params.Set("_query_only", "on")
+	} else {
+		params.Set("_txlock", "immediate")
+	}
+
+	if path == memory {
+		return path + "?" + params.Encode()
+	}
+
+	escaped := strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+	return "file:" + escaped + "?" + params.Encode()
+}
+
+// Open opens the database at path. An in-memory database only exists for
+// the connection that opened it, so both pools are the same single
+// connection.
+func Open(path string) (*DB, error) {
+	write, err := sql.Open("sqlite3", DSN(path, false))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open database connection: %w", err)
+	}
+	write.SetMaxOpenConns(1)
+
+	if path == memory {
+		return &DB{Read: write, Write: write}, nil
+	}
+
+	read, err := sql.Open("sqlite3", DSN(path, true))
+	if err != nil {
+		write.Close()
+		return nil, fmt.Errorf("couldn't open database connection: %w", err)
+	}
+	conns := max(4, runtime.NumCPU())
+	read.SetMaxOpenConns(conns)
+	read.SetMaxIdleConns(conns)
+
+	return &DB{Read: read, Write: write}, nil
+}
+
+// Close closes both pools, waiting for queries in flight to finish.
+func (db *DB) Close() error {
+	if db.Read == db.Write {
+		return db.Write.Close()
+	}
+
+	return errors.Join(db.Read.Close(), db.Write.Close())
+}
+
+// pool returns the pool to run query in. Anything that isn't plainly a read
+// goes to the write pool, which can run either.
+func (db *DB) pool(query string) *sql.DB {
+	if isRead(query) {
+		return db.Read
+	}
+
+	return db.Write
+}
+
+// isRead reports whether query, after any leading comments like the names
+// sqlc gives its queries, is a SELECT.
+func isRead(query string) bool {
+	for {
+		query = strings.TrimSpace(query)
+		if !strings.HasPrefix(query, "--") {
+			break
+		}
+
+		_, query, _ = strings.Cut(query, "\n")
+	}
+
+	keyword, _, _ := strings.Cut(query, " ")
+	keyword, _, _ = strings.Cut(keyword, "\n")
+	return strings.EqualFold(keyword, "SELECT")
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.Write.ExecContext(ctx, query, args...)
+}
+
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.pool(query).PrepareContext(ctx, query)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.pool(query).QueryContext(ctx, query, args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.pool(query).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx begins a transaction on the write pool.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.Write.BeginTx(ctx, opts)
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestIsRead(t *testing.T) {
+	for query, expected := range map[string]bool{
+		"SELECT 1": true,
+		"-- name: GetObservation :one\nSELECT\n  *": true,
+		"  select * from observations":              true,
+		"-- name: AddGeolocation :one\nINSERT INTO": false,
+		"WITH recent AS (SELECT 1) DELETE FROM x":   false,
+		"PRAGMA journal_mode":                       false,
+	} {
+		if got := isRead(query); got != expected {
+			t.Errorf("expected isRead(%q) to be %v", query, expected)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := Open(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE parents (id INTEGER PRIMARY KEY);
+		CREATE TABLE children (parent_id INTEGER NOT NULL REFERENCES parents(id));
+	`); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	t.Run("uses WAL", func(t *testing.T) {
+		var mode string
+		if err := db.Read.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+			t.Errorf("expected wal, got %q, %v", mode, err)
+		}
+	})
+
+	t.Run("enforces foreign keys", func(t *testing.T) {
+		if _, err := db.ExecContext(ctx, "INSERT INTO children (parent_id) VALUES (42)"); err == nil {
+			t.Errorf("expected the missing parent to be refused")
+		}
+	})
+
+	t.Run("keeps reads from writing", func(t *testing.T) {
+		if _, err := db.Read.ExecContext(ctx, "INSERT INTO parents (id) VALUES (1)"); err == nil {
+			t.Errorf("expected the read pool to refuse writes")
+		}
+	})
+
+	t.Run("serializes concurrent writes", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 50)
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				row := db.QueryRowContext(ctx, "INSERT INTO parents (id) VALUES (?) RETURNING id", i+100)
+				var id int64
+				errs <- row.Scan(&id)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM parents").Scan(&count); err != nil || count != 50 {
+			t.Errorf("expected 50 parents, got %d, %v", count, err)
+		}
+	})
+}
+
+func TestOpenMemory(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer db.Close()
+
+	if db.Read != db.Write {
+		t.Errorf("expected an in-memory database to share one pool")
+	}
+}
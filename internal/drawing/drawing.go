@@ -1,5 +1,222 @@
 package drawing
 
-func Validate(drawing string) error {
+import (
+	"weather/internal/validation"
+
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+// Version is the current version of the drawing encoding.
+//
+// A version 1 drawing is a header followed by a stream of strokes, with all
+// integers big-endian:
+//
+//	header: version uint8, width uint16, height uint16
+//	stroke: colorIndex uint8, sizeIndex uint8, pointCount uint16
+//	point:  x uint16, y uint16
+const Version uint8 = 1
+
+const (
+	headerSize = 5
+	strokeSize = 4
+	pointSize  = 4
+)
+
+// MaxStrokePoints is the most points a stroke may have. It's well within what
+// pointCount can hold, and the observation-canvas component splits longer
+// strokes to stay under it, so a count past it is a client gone wrong.
+const MaxStrokePoints = 4096
+
+type Point struct {
+	X uint16
+	Y uint16
+}
+
+// Stroke is a run of points painted with a single palette color and brush
+// size, each given as an index into Palette.
+type Stroke struct {
+	Color  uint8
+	Size   uint8
+	Points []Point
+}
+
+type Drawing struct {
+	Width   uint16
+	Height  uint16
+	Strokes []Stroke
+}
+
+// Palette is the set of colors and brush sizes a drawing can index into. It
+// is the same model the observation-canvas-pallete component uses.
+type Palette struct {
+	Colors     []string
+	BrushSizes []int
+}
+
+var DefaultPalette = Palette{
+	Colors:     []string{"#ff0000", "#00ff00", "#0000ff"},
+	BrushSizes: []int{1, 10, 100},
+}
+
+// Limits bounds what a submitted drawing may contain.
+type Limits struct {
+	Width      uint16
+	Height     uint16
+	MaxBytes   int
+	MaxStrokes int
+	Palette    Palette
+}
+
+var DefaultLimits = Limits{
+	Width:      500,
+	Height:     500,
+	MaxBytes:   64 * 1024,
+	MaxStrokes: 512,
+	Palette:    DefaultPalette,
+}
+
+func Encode(d Drawing) ([]byte, error) {
+	size := headerSize
+	for i, stroke := range d.Strokes {
+		if len(stroke.Points) > MaxStrokePoints {
+			return nil, fmt.Errorf("stroke %d has too many points: %d", i, len(stroke.Points))
+		}
+		size += strokeSize + pointSize*len(stroke.Points)
+	}
+
+	b := make([]byte, 0, size)
+	b = append(b, Version)
+	b = binary.BigEndian.AppendUint16(b, d.Width)
+	b = binary.BigEndian.AppendUint16(b, d.Height)
+
+	for _, stroke := range d.Strokes {
+		b = append(b, stroke.Color, stroke.Size)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(stroke.Points)))
+		for _, p := range stroke.Points {
+			b = binary.BigEndian.AppendUint16(b, p.X)
+			b = binary.BigEndian.AppendUint16(b, p.Y)
+		}
+	}
+
+	return b, nil
+}
+
+func Decode(b []byte) (Drawing, error) {
+	d := Drawing{}
+
+	if len(b) < headerSize {
+		return d, validation.Problem("drawing", "is shorter than its header")
+	}
+
+	if b[0] != Version {
+		return d, validation.Problem("drawing", "uses unsupported version %d", b[0])
+	}
+
+	d.Width = binary.BigEndian.Uint16(b[1:])
+	d.Height = binary.BigEndian.Uint16(b[3:])
+	b = b[headerSize:]
+
+	for len(b) > 0 {
+		if len(b) < strokeSize {
+			return d, validation.Problem("drawing", "stroke %d is truncated", len(d.Strokes))
+		}
+
+		stroke := Stroke{Color: b[0], Size: b[1]}
+		count := int(binary.BigEndian.Uint16(b[2:]))
+		b = b[strokeSize:]
+
+		if count > MaxStrokePoints {
+			return d, validation.Problem("drawing", "stroke %d has %d points, more than %d", len(d.Strokes), count, MaxStrokePoints)
+		}
+
+		if len(b) < count*pointSize {
+			return d, validation.Problem("drawing", "stroke %d is truncated", len(d.Strokes))
+		}
+
+		stroke.Points = make([]Point, count)
+		for i := range stroke.Points {
+			stroke.Points[i] = Point{
+				X: binary.BigEndian.Uint16(b[i*pointSize:]),
+				Y: binary.BigEndian.Uint16(b[i*pointSize+2:]),
+			}
+		}
+		b = b[count*pointSize:]
+
+		d.Strokes = append(d.Strokes, stroke)
+	}
+
+	return d, nil
+}
+
+// Check reports the first way d falls outside of l.
+func (l Limits) Check(d Drawing) error {
+	if d.Width == 0 || d.Height == 0 || d.Width > l.Width || d.Height > l.Height {
+		return validation.Problem("drawing", "canvas %dx%d is outside of %dx%d", d.Width, d.Height, l.Width, l.Height)
+	}
+
+	if len(d.Strokes) > l.MaxStrokes {
+		return validation.Problem("drawing", "has %d strokes, more than %d", len(d.Strokes), l.MaxStrokes)
+	}
+
+	for i, stroke := range d.Strokes {
+		if int(stroke.Color) >= len(l.Palette.Colors) {
+			return validation.Problem("drawing", "stroke %d uses unknown color %d", i, stroke.Color)
+		}
+
+		if int(stroke.Size) >= len(l.Palette.BrushSizes) {
+			return validation.Problem("drawing", "stroke %d uses unknown brush size %d", i, stroke.Size)
+		}
+
+		for _, p := range stroke.Points {
+			if p.X >= d.Width || p.Y >= d.Height {
+				return validation.Problem("drawing", "stroke %d point (%d, %d) is off the canvas", i, p.X, p.Y)
+			}
+		}
+	}
+
 	return nil
 }
+
+// Parse decodes a base64 encoded drawing as submitted by the
+// observation-canvas component and checks it against l.
+func (l Limits) Parse(encoded string) (Drawing, error) {
+	if base64.StdEncoding.DecodedLen(len(encoded)) > l.MaxBytes+2 {
+		return Drawing{}, validation.Problem("drawing", "is larger than %d bytes", l.MaxBytes)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return Drawing{}, validation.Problem("drawing", "is not valid base64")
+	}
+
+	if len(b) > l.MaxBytes {
+		return Drawing{}, validation.Problem("drawing", "is larger than %d bytes", l.MaxBytes)
+	}
+
+	d, err := Decode(b)
+	if err != nil {
+		return d, err
+	}
+
+	return d, l.Check(d)
+}
+
+// ParseStored decodes a base64 encoded drawing that was checked against the
+// limits in force when it was submitted. Only the encoding is checked, since
+// limits tightened since then mustn't make stored drawings unreadable.
+func ParseStored(encoded string) (Drawing, error) {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return Drawing{}, fmt.Errorf("error decoding stored drawing: %w", err)
+	}
+
+	return Decode(b)
+}
+
+// Validate checks a base64 encoded drawing against DefaultLimits.
+func Validate(drawing string) error {
+	_, err := DefaultLimits.Parse(drawing)
+	return err
+}
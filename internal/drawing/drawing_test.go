@@ -0,0 +1,110 @@
+package drawing
+
+import (
+	"weather/internal/validation"
+
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	d := Drawing{
+		Width:  500,
+		Height: 500,
+		Strokes: []Stroke{
+			{Color: 0, Size: 1, Points: []Point{{1, 2}, {3, 4}}},
+			{Color: 2, Size: 0, Points: []Point{{499, 499}}},
+		},
+	}
+
+	b, err := Encode(d)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	t.Run("round trips", func(t *testing.T) {
+		decoded, err := Decode(b)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !reflect.DeepEqual(d, decoded) {
+			t.Errorf("expected %+v, got %+v", d, decoded)
+		}
+	})
+
+	t.Run("validates", func(t *testing.T) {
+		if err := Validate(base64.StdEncoding.EncodeToString(b)); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+
+	t.Run("rejects truncated data", func(t *testing.T) {
+		if _, err := Decode(b[:len(b)-1]); !errors.Is(err, validation.ErrValidation) {
+			t.Errorf("expected validation error, got %v", err)
+		}
+	})
+
+	t.Run("rejects strokes with too many points", func(t *testing.T) {
+		long := Drawing{Width: 10, Height: 10, Strokes: []Stroke{{Points: make([]Point, MaxStrokePoints+1)}}}
+		if _, err := Encode(long); err == nil {
+			t.Errorf("expected an error encoding %d points", MaxStrokePoints+1)
+		}
+
+		long.Strokes[0].Points = long.Strokes[0].Points[:MaxStrokePoints]
+		b, err := Encode(long)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		binary.BigEndian.PutUint16(b[headerSize+2:], MaxStrokePoints+1)
+		b = binary.BigEndian.AppendUint32(b, 0)
+
+		if _, err := Decode(b); !errors.Is(err, validation.ErrValidation) {
+			t.Errorf("expected validation error, got %v", err)
+		}
+	})
+
+	t.Run("reads stored drawings beyond tighter limits", func(t *testing.T) {
+		tighter := DefaultLimits
+		tighter.Width, tighter.Height = 100, 100
+		encoded := base64.StdEncoding.EncodeToString(b)
+
+		if _, err := tighter.Parse(encoded); err == nil {
+			t.Fatalf("expected the drawing to be outside of the tighter limits")
+		}
+		if decoded, err := ParseStored(encoded); err != nil || !reflect.DeepEqual(d, decoded) {
+			t.Errorf("expected %+v, got %+v (%v)", d, decoded, err)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cases := map[string]Drawing{
+		"empty canvas":     {Width: 0, Height: 500},
+		"oversized canvas": {Width: 501, Height: 500},
+		"unknown color":    {Width: 10, Height: 10, Strokes: []Stroke{{Color: 3}}},
+		"unknown size":     {Width: 10, Height: 10, Strokes: []Stroke{{Size: 3}}},
+		"point off canvas": {Width: 10, Height: 10, Strokes: []Stroke{{Points: []Point{{10, 0}}}}},
+		"too many strokes": {Width: 10, Height: 10, Strokes: make([]Stroke, DefaultLimits.MaxStrokes+1)},
+	}
+
+	for name, d := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Encode(d)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if err := Validate(base64.StdEncoding.EncodeToString(b)); !errors.Is(err, validation.ErrValidation) {
+				t.Errorf("expected validation error, got %v", err)
+			}
+		})
+	}
+
+	t.Run("rejects garbage", func(t *testing.T) {
+		if err := Validate("not base64!"); !errors.Is(err, validation.ErrValidation) {
+			t.Errorf("expected validation error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package drawing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// Render rasterizes d the way the observation-canvas component paints it:
+// every point is a filled circle whose radius is the stroke's brush size.
+// Unpainted pixels are left transparent, as are strokes outside of p.
+func Render(d Drawing, p Palette) (*image.RGBA, error) {
+	colors := make([]color.RGBA, len(p.Colors))
+	for i, hex := range p.Colors {
+		c, err := parseHexColor(hex)
+		if err != nil {
+			return nil, err
+		}
+		colors[i] = c
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, int(d.Width), int(d.Height)))
+
+	for _, stroke := range d.Strokes {
+		// Drawings are kept as they were submitted, so one made with an
+		// earlier palette may use colors or sizes this one doesn't have.
+		if int(stroke.Color) >= len(colors) || int(stroke.Size) >= len(p.BrushSizes) {
+			continue
+		}
+
+		c := colors[stroke.Color]
+		r := p.BrushSizes[stroke.Size]
+		for _, pt := range stroke.Points {
+			fillCircle(img, int(pt.X), int(pt.Y), r, c)
+		}
+	}
+
+	return img, nil
+}
+
+// RenderPNG renders d and encodes it as a PNG.
+func RenderPNG(d Drawing, p Palette) ([]byte, error) {
+	img, err := Render(d, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error encoding drawing as PNG: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func fillCircle(img *image.RGBA, cx int, cy int, r int, c color.RGBA) {
+	bounds := image.Rect(cx-r, cy-r, cx+r+1, cy+r+1).Intersect(img.Bounds())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dx, dy := x-cx, y-cy
+			if dx*dx+dy*dy <= r*r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// Hash identifies p, so images rendered with it can be cached until it is
+// reconfigured.
+func (p Palette) Hash() string {
+	h := sha256.New()
+	for _, c := range p.Colors {
+		fmt.Fprintf(h, "color %s\n", strings.ToLower(c))
+	}
+	for _, size := range p.BrushSizes {
+		fmt.Fprintf(h, "size %d\n", size)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// CheckPalette reports whether every color in p can be rendered.
+func CheckPalette(p Palette) error {
+	for _, hex := range p.Colors {
+		if _, err := parseHexColor(hex); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseHexColor parses CSS style #rgb and #rrggbb colors.
+func parseHexColor(hex string) (color.RGBA, error) {
+	s := strings.TrimPrefix(hex, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid palette color: %q", hex)
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid palette color: %q", hex)
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
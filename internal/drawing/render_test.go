@@ -0,0 +1,55 @@
+package drawing
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	d := Drawing{
+		Width:  20,
+		Height: 20,
+		Strokes: []Stroke{
+			{Color: 1, Size: 0, Points: []Point{{5, 5}}},
+			{Color: 3, Size: 0, Points: []Point{{10, 10}}},
+		},
+	}
+
+	img, err := Render(d, DefaultPalette)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	t.Run("paints points with the palette color", func(t *testing.T) {
+		if c := img.RGBAAt(5, 6); c != (color.RGBA{0, 0xff, 0, 0xff}) {
+			t.Errorf("expected green, got %v", c)
+		}
+	})
+
+	t.Run("leaves the rest transparent", func(t *testing.T) {
+		if c := img.RGBAAt(15, 15); c.A != 0 {
+			t.Errorf("expected transparent, got %v", c)
+		}
+	})
+
+	t.Run("skips strokes outside of the palette", func(t *testing.T) {
+		if c := img.RGBAAt(10, 10); c.A != 0 {
+			t.Errorf("expected transparent, got %v", c)
+		}
+	})
+}
+
+func TestPaletteHash(t *testing.T) {
+	changed := Palette{
+		Colors:     append([]string{}, DefaultPalette.Colors...),
+		BrushSizes: DefaultPalette.BrushSizes,
+	}
+	changed.Colors[0] = "#000000"
+
+	if DefaultPalette.Hash() != DefaultPalette.Hash() {
+		t.Errorf("expected the same palette to hash the same")
+	}
+	if DefaultPalette.Hash() == changed.Hash() {
+		t.Errorf("expected a changed color to change the hash")
+	}
+}
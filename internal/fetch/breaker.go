@@ -0,0 +1,263 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting a host while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// ErrRateLimited is returned without contacting a host that has told us we
+// are out of requests until after the request's deadline.
+var ErrRateLimited = errors.New("rate limited")
+
+// BreakerState is the state of a host's circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single probe through to test whether the host
+	// has recovered.
+	BreakerHalfOpen
+	// BreakerOpen rejects requests until the cooldown has passed.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerPolicy decides when a host's breaker opens. It opens once at least
+// MinRequests of the last Window requests have completed and FailureRatio of
+// them failed, and stays open for Cooldown.
+type BreakerPolicy struct {
+	Window       int
+	MinRequests  int
+	FailureRatio float64
+	Cooldown     time.Duration
+}
+
+var DefaultBreakerPolicy = BreakerPolicy{
+	Window:       20,
+	MinRequests:  10,
+	FailureRatio: 0.5,
+	Cooldown:     30 * time.Second,
+}
+
+// HostStatus is a snapshot of a host's breaker, for metrics.
+type HostStatus struct {
+	Host     string
+	State    BreakerState
+	Requests uint64
+	Failures uint64
+	Retries  uint64
+	Rejected uint64
+}
+
+// Breakers holds a circuit breaker per host.
+type Breakers struct {
+	policy BreakerPolicy
+	now    func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+type breaker struct {
+	state    BreakerState
+	openedAt time.Time
+	probing  bool
+
+	// outcomes is a ring of the last policy.Window results, true for
+	// failures.
+	outcomes []bool
+	next     int
+
+	// exhaustedUntil is when the host said our rate limit resets.
+	exhaustedUntil time.Time
+
+	requests, failures, retries, rejected uint64
+}
+
+func NewBreakers(policy BreakerPolicy) *Breakers {
+	return &Breakers{
+		policy: policy,
+		now:    time.Now,
+		hosts:  map[string]*breaker{},
+	}
+}
+
+func (b *Breakers) host(host string) *breaker {
+	h, ok := b.hosts[host]
+	if !ok {
+		h = &breaker{}
+		b.hosts[host] = h
+	}
+	return h
+}
+
+// allow reports whether a request to host may go ahead, marking it as the
+// probe when the breaker is half-open.
+func (b *Breakers) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.host(host)
+	h.state = b.stateOf(h)
+
+	switch {
+	case h.state == BreakerOpen, h.state == BreakerHalfOpen && h.probing:
+		h.rejected++
+		return fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+	case h.state == BreakerHalfOpen:
+		h.probing = true
+	}
+
+	h.requests++
+	return nil
+}
+
+// record notes the outcome of a request allowed by allow.
+func (b *Breakers) record(host string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.host(host)
+	if failed {
+		h.failures++
+	}
+
+	if h.state == BreakerHalfOpen {
+		h.probing = false
+		if failed {
+			b.open(h)
+		} else {
+			h.state = BreakerClosed
+		}
+		return
+	}
+
+	if len(h.outcomes) < b.policy.Window {
+		h.outcomes = append(h.outcomes, failed)
+	} else {
+		h.outcomes[h.next] = failed
+		h.next = (h.next + 1) % len(h.outcomes)
+	}
+
+	if len(h.outcomes) < b.policy.MinRequests {
+		return
+	}
+
+	failures := 0
+	for _, outcome := range h.outcomes {
+		if outcome {
+			failures++
+		}
+	}
+	if float64(failures) >= b.policy.FailureRatio*float64(len(h.outcomes)) {
+		b.open(h)
+	}
+}
+
+// stateOf returns h's state, moving open breakers whose cooldown has passed
+// to half-open.
+func (b *Breakers) stateOf(h *breaker) BreakerState {
+	if h.state == BreakerOpen && b.now().Sub(h.openedAt) >= b.policy.Cooldown {
+		return BreakerHalfOpen
+	}
+	return h.state
+}
+
+func (b *Breakers) open(h *breaker) {
+	h.state = BreakerOpen
+	h.openedAt = b.now()
+	h.outcomes = h.outcomes[:0]
+	h.next = 0
+}
+
+// release notes that a request allowed by allow ended without telling us
+// anything about the host, like when the caller gave up on it.
+func (b *Breakers) release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.host(host).probing = false
+}
+
+func (b *Breakers) retried(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.host(host).retries++
+}
+
+// exhaust notes that host won't serve us again until reset.
+func (b *Breakers) exhaust(host string, reset time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.host(host)
+	if reset.After(h.exhaustedUntil) {
+		h.exhaustedUntil = reset
+	}
+}
+
+// exhausted returns how long until host's rate limit resets, or zero.
+func (b *Breakers) exhausted(host string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wait := b.host(host).exhaustedUntil.Sub(b.now())
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// State returns host's breaker state.
+func (b *Breakers) State(host string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stateOf(b.host(host))
+}
+
+// Snapshot returns the status of every host contacted so far, ordered by
+// host.
+func (b *Breakers) Snapshot() []HostStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := make([]HostStatus, 0, len(b.hosts))
+	for host, h := range b.hosts {
+		statuses = append(statuses, HostStatus{
+			Host:     host,
+			State:    b.stateOf(h),
+			Requests: h.requests,
+			Failures: h.failures,
+			Retries:  h.retries,
+			Rejected: h.rejected,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Host < statuses[j].Host
+	})
+
+	return statuses
+}
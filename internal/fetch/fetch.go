@@ -3,33 +3,230 @@ package fetch
 import (
 	"weather/internal/validation"
 
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
-func JSON[T validation.Validates](url string, into *T) error {
-	response, err := http.Get(url)
+// userAgent identifies us to upstream APIs, some of which (MET Norway, NWS)
+// reject anonymous requests.
+const userAgent = "weather-app (+https://github.com/collinthefarmer/weather-app)"
+
+// maxErrorBody is how much of an unsuccessful response body is kept for
+// StatusError.
+const maxErrorBody = 512
+
+// maxDrain bounds how much of an unread body is discarded so the connection
+// can be reused.
+const maxDrain = 64 * 1024
+
+// StatusError is returned when an upstream responds with a non-200 status.
+type StatusError struct {
+	Host       string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s responded with status %d: %s", e.Host, e.StatusCode, e.Body)
+}
+
+// RequestError is returned when an upstream couldn't be reached or its
+// response couldn't be read.
+type RequestError struct {
+	Host string
+	Err  error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("error contacting %s: %v", e.Host, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is returned when an upstream's response isn't the JSON we
+// expected.
+type DecodeError struct {
+	Host string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("error decoding JSON from %s: %v", e.Host, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when an upstream's response decodes but fails
+// its Validate method. The problems are the upstream's rather than the
+// caller's, so it deliberately doesn't wrap validation.ErrValidation and is
+// only found with errors.As.
+type ValidationError struct {
+	Host     string
+	Problems validation.ValidationProblems
+}
+
+func (e *ValidationError) Error() string {
+	problems := &validation.ValidationError{Problems: e.Problems}
+	return fmt.Sprintf("invalid response from %s: %v", e.Host, problems)
+}
+
+// Options configures the client built by NewClient.
+type Options struct {
+	// Timeout bounds each request to hosts without an entry in HostTimeouts,
+	// including any retries. Running out of it counts against the host's
+	// breaker.
+	Timeout      time.Duration
+	HostTimeouts map[string]time.Duration
+
+	Retry RetryPolicy
+
+	// Breakers tracks the health of each host, NewBreakers(DefaultBreakerPolicy)
+	// when nil.
+	Breakers *Breakers
+}
+
+// NewClient returns an http.Client that bounds each request by the timeout
+// for its host, retries failed idempotent requests and fails fast while a
+// host's circuit breaker is open.
+func NewClient(opts Options) *http.Client {
+	if opts.Breakers == nil {
+		opts.Breakers = NewBreakers(DefaultBreakerPolicy)
+	}
+
+	return &http.Client{
+		Transport: &timeoutTransport{
+			next: &retryTransport{
+				next:     http.DefaultTransport,
+				policy:   opts.Retry,
+				breakers: opts.Breakers,
+			},
+			timeouts: opts.HostTimeouts,
+			fallback: opts.Timeout,
+		},
+	}
+}
+
+// DefaultClient is used by providers that aren't given a client.
+var DefaultClient = NewClient(Options{
+	Timeout: 10 * time.Second,
+	Retry:   DefaultRetryPolicy,
+})
+
+// errHostTimeout is the cause of a request's context ending because its
+// host's timeout passed, which unlike the caller giving up says the host is
+// unhealthy. It is still a context.DeadlineExceeded to callers.
+var errHostTimeout = fmt.Errorf("host timed out: %w", context.DeadlineExceeded)
+
+type timeoutTransport struct {
+	next     http.RoundTripper
+	timeouts map[string]time.Duration
+	fallback time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout, ok := t.timeouts[req.URL.Hostname()]
+	if !ok {
+		timeout = t.fallback
+	}
+
+	ctx, cancel := context.WithTimeoutCause(req.Context(), timeout, errHostTimeout)
+	response, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The timeout has to cover reading the body too, so it is only released
+	// once the body is closed.
+	response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// ParseHostTimeouts parses per-host timeouts written as host=duration.
+func ParseHostTimeouts(entries []string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, entry := range entries {
+		host, raw, ok := strings.Cut(entry, "=")
+		if !ok || host == "" {
+			return nil, fmt.Errorf("host timeout %q isn't written as host=duration", entry)
+		}
+
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("host timeout %q has an invalid duration", entry)
+		}
+
+		timeouts[host] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// JSON fetches endpoint with client, decodes its JSON body into into and
+// validates it. A nil client uses DefaultClient.
+func JSON[T validation.Validates](ctx context.Context, client *http.Client, endpoint string, into *T) error {
+	if client == nil {
+		client = DefaultClient
+	}
+
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil {
+		host = u.Host
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return &RequestError{Host: host, Err: err}
+	}
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.Do(request)
 	if err != nil {
-		return fmt.Errorf("error contacting IP-API: %w", err)
+		return &RequestError{Host: host, Err: err}
 	}
+	defer func() {
+		io.Copy(io.Discard, io.LimitReader(response.Body, maxDrain))
+		response.Body.Close()
+	}()
 
-	if response.StatusCode != 200 {
-		return fmt.Errorf("non-200 status code returned from endpoint: %v", response.StatusCode)
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		return &StatusError{Host: host, StatusCode: response.StatusCode, Body: string(body)}
 	}
 
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
+		return &RequestError{Host: host, Err: err}
 	}
 
 	if err := json.Unmarshal(body, into); err != nil {
-		return fmt.Errorf("error decodings JSON from IP-API response body: %w", err)
+		return &DecodeError{Host: host, Err: err}
 	}
 
 	if problems, err := (*into).Validate(); err != nil {
-		return fmt.Errorf("error validating response: %s", problems)
+		return &ValidationError{Host: host, Problems: problems}
 	}
 
 	return nil
@@ -0,0 +1,138 @@
+package fetch
+
+import (
+	"weather/internal/validation"
+
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type payload struct {
+	Value int `json:"value"`
+}
+
+func (p payload) Validate() (validation.ValidationProblems, error) {
+	return nil, nil
+}
+
+func TestJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"value": 7}`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{"value": 7}`))
+		default:
+			http.Error(w, strings.Repeat("x", 2*maxErrorBody), http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := NewClient(Options{
+		Timeout:      time.Second,
+		HostTimeouts: map[string]time.Duration{u.Hostname(): 50 * time.Millisecond},
+		Retry:        RetryPolicy{Attempts: 1},
+	})
+
+	t.Run("decodes responses", func(t *testing.T) {
+		p := payload{}
+		if err := JSON(context.Background(), client, server.URL+"/ok", &p); err != nil || p.Value != 7 {
+			t.Errorf("expected 7, got %v (%v)", p.Value, err)
+		}
+	})
+
+	t.Run("returns status errors", func(t *testing.T) {
+		err := JSON(context.Background(), client, server.URL+"/limited", &payload{})
+
+		statusErr := &StatusError{}
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("expected a StatusError, got %v", err)
+		}
+		if statusErr.StatusCode != http.StatusTooManyRequests || statusErr.Host != u.Host || len(statusErr.Body) != maxErrorBody {
+			t.Errorf("unexpected error: %+v", statusErr)
+		}
+	})
+
+	t.Run("applies host timeouts", func(t *testing.T) {
+		err := JSON(context.Background(), client, server.URL+"/slow", &payload{})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected a timeout, got %v", err)
+		}
+	})
+
+	t.Run("honors cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := JSON(ctx, client, server.URL+"/ok", &payload{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected cancellation, got %v", err)
+		}
+	})
+}
+
+func TestHostTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`{"value": 7}`))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	newClient := func(breakers *Breakers) *http.Client {
+		return NewClient(Options{
+			Timeout:      time.Second,
+			HostTimeouts: map[string]time.Duration{u.Hostname(): 20 * time.Millisecond},
+			Retry:        RetryPolicy{Attempts: 1},
+			Breakers:     breakers,
+		})
+	}
+	policy := BreakerPolicy{Window: 3, MinRequests: 3, FailureRatio: 0.5, Cooldown: time.Minute}
+
+	t.Run("counts timeouts against the host", func(t *testing.T) {
+		breakers := NewBreakers(policy)
+		client := newClient(breakers)
+
+		for range policy.MinRequests {
+			if err := JSON(context.Background(), client, server.URL, &payload{}); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected a timeout, got %v", err)
+			}
+		}
+
+		if state := breakers.State(u.Host); state != BreakerOpen {
+			t.Errorf("expected the breaker to open, got %v", state)
+		}
+		if err := JSON(context.Background(), client, server.URL, &payload{}); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected the open breaker to reject the request, got %v", err)
+		}
+	})
+
+	t.Run("doesn't count callers giving up", func(t *testing.T) {
+		breakers := NewBreakers(policy)
+		client := newClient(breakers)
+
+		for range policy.MinRequests {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+			err := JSON(ctx, client, server.URL, &payload{})
+			cancel()
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected the caller's deadline, got %v", err)
+			}
+		}
+
+		status := breakers.Snapshot()
+		if len(status) != 1 || status[0].State != BreakerClosed || status[0].Failures != 0 {
+			t.Errorf("expected a closed breaker without failures, got %+v", status)
+		}
+	})
+}
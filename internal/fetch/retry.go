@@ -0,0 +1,182 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides how failed idempotent requests are retried. Delays
+// grow exponentially from BaseDelay with jitter, unless the host says how
+// long to wait. Hosts asking for more than MaxDelay aren't retried.
+type RetryPolicy struct {
+	// Attempts is how many times a request is tried, 1 disables retries.
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:  3,
+	BaseDelay: 200 * time.Millisecond,
+	MaxDelay:  5 * time.Second,
+}
+
+type retryTransport struct {
+	next     http.RoundTripper
+	policy   RetryPolicy
+	breakers *Breakers
+
+	// sleep waits for d or until ctx is done, replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	ctx := req.Context()
+
+	attempts := t.policy.Attempts
+	if attempts < 1 || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		attempts = 1
+	}
+
+	for attempt := 0; ; attempt++ {
+		if wait := t.breakers.exhausted(host); wait > 0 {
+			if wait > t.policy.MaxDelay || !fits(ctx, wait) {
+				return nil, fmt.Errorf("%w by %s for another %v", ErrRateLimited, host, wait.Round(time.Second))
+			}
+			if err := t.wait(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := t.breakers.allow(host); err != nil {
+			return nil, err
+		}
+
+		response, err := t.next.RoundTrip(req)
+		if err != nil && ctx.Err() != nil && !errors.Is(context.Cause(ctx), errHostTimeout) {
+			// The caller gave up, which says nothing about the host. Running
+			// out of the host's own timeout does, so that is a failure.
+			t.breakers.release(host)
+			return nil, err
+		}
+
+		hint := t.noteRateLimit(host, response)
+		failed := err != nil || response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+		t.breakers.record(host, failed)
+
+		if !failed || !retryable(response) || attempt+1 >= attempts {
+			return response, err
+		}
+
+		delay := hint
+		if delay == 0 {
+			delay = t.backoff(attempt)
+		}
+		if delay > t.policy.MaxDelay || !fits(ctx, delay) {
+			return response, err
+		}
+
+		if response != nil {
+			io.Copy(io.Discard, io.LimitReader(response.Body, maxDrain))
+			response.Body.Close()
+		}
+
+		t.breakers.retried(host)
+		if err := t.wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether a failed request is worth trying again.
+// Transport errors are, as are statuses that mean the host is overloaded or
+// briefly unavailable.
+func retryable(response *http.Response) bool {
+	if response == nil {
+		return true
+	}
+
+	switch response.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// noteRateLimit records any rate limit response advertises and returns how
+// long the host asked us to wait before trying again, or zero.
+//
+// Retry-After is standard. ip-api instead sends X-Rl, the requests left in
+// the current window, and X-Ttl, the seconds until the window resets, and
+// bans clients that keep going once X-Rl reaches 0.
+func (t *retryTransport) noteRateLimit(host string, response *http.Response) time.Duration {
+	if response == nil {
+		return 0
+	}
+
+	var wait time.Duration
+
+	if remaining, err := strconv.Atoi(response.Header.Get("X-Rl")); err == nil && remaining <= 0 {
+		if ttl, err := strconv.Atoi(response.Header.Get("X-Ttl")); err == nil && ttl > 0 {
+			wait = time.Duration(ttl) * time.Second
+			t.breakers.exhaust(host, t.breakers.now().Add(wait))
+		}
+	}
+
+	if after := response.Header.Get("Retry-After"); after != "" {
+		if seconds, err := strconv.Atoi(after); err == nil && seconds >= 0 {
+			wait = max(wait, time.Duration(seconds)*time.Second)
+		} else if at, err := http.ParseTime(after); err == nil {
+			wait = max(wait, at.Sub(t.breakers.now()))
+		}
+	}
+
+	return wait
+}
+
+// backoff returns the delay before retry attempt+1, doubling from BaseDelay
+// up to MaxDelay, with the upper half jittered so clients that failed
+// together don't retry together.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	delay := t.policy.BaseDelay << attempt
+	if delay <= 0 || delay > t.policy.MaxDelay {
+		delay = t.policy.MaxDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func (t *retryTransport) wait(ctx context.Context, d time.Duration) error {
+	if t.sleep != nil {
+		return t.sleep(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fits reports whether waiting d leaves ctx time to make another request.
+func fits(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
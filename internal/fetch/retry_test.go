@@ -0,0 +1,160 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var calls atomic.Int32
+	var statuses []int
+	var headers []http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n < len(statuses) {
+			for key, values := range headers[n] {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n])
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	newClient := func(breakers *Breakers) *http.Client {
+		return &http.Client{Transport: &retryTransport{
+			next:     http.DefaultTransport,
+			policy:   RetryPolicy{Attempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Minute},
+			breakers: breakers,
+			sleep: func(ctx context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			},
+		}}
+	}
+	reset := func(s []int, h ...http.Header) {
+		calls.Store(0)
+		statuses, headers, delays = s, h, nil
+	}
+	get := func(client *http.Client) (*http.Response, error) {
+		response, err := client.Get(server.URL)
+		if err == nil {
+			response.Body.Close()
+		}
+		return response, err
+	}
+
+	t.Run("retries with backoff", func(t *testing.T) {
+		reset([]int{503, 502}, http.Header{}, http.Header{})
+
+		response, err := get(newClient(NewBreakers(DefaultBreakerPolicy)))
+		if err != nil || response.StatusCode != http.StatusOK {
+			t.Fatalf("expected success, got %v %v", response, err)
+		}
+		if calls.Load() != 3 || len(delays) != 2 {
+			t.Fatalf("expected 3 calls and 2 delays, got %d and %v", calls.Load(), delays)
+		}
+		if delays[0] < 50*time.Millisecond || delays[0] > 100*time.Millisecond ||
+			delays[1] < 100*time.Millisecond || delays[1] > 200*time.Millisecond {
+			t.Errorf("unexpected delays: %v", delays)
+		}
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		reset([]int{500, 500, 500}, http.Header{}, http.Header{}, http.Header{})
+
+		response, err := get(newClient(NewBreakers(DefaultBreakerPolicy)))
+		if err != nil || response.StatusCode != http.StatusInternalServerError || calls.Load() != 3 {
+			t.Errorf("expected the third 500, got %v %v after %d calls", response, err, calls.Load())
+		}
+	})
+
+	t.Run("doesn't retry client errors", func(t *testing.T) {
+		reset([]int{404}, http.Header{})
+
+		response, err := get(newClient(NewBreakers(DefaultBreakerPolicy)))
+		if err != nil || response.StatusCode != http.StatusNotFound || calls.Load() != 1 {
+			t.Errorf("expected a single 404, got %v %v after %d calls", response, err, calls.Load())
+		}
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		reset([]int{429}, http.Header{"Retry-After": {"7"}})
+
+		if _, err := get(newClient(NewBreakers(DefaultBreakerPolicy))); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(delays) != 1 || delays[0] != 7*time.Second {
+			t.Errorf("expected to wait 7s, got %v", delays)
+		}
+	})
+
+	t.Run("honors ip-api rate limits", func(t *testing.T) {
+		reset([]int{200}, http.Header{"X-Rl": {"0"}, "X-Ttl": {"30"}})
+		breakers := NewBreakers(DefaultBreakerPolicy)
+		client := newClient(breakers)
+
+		if _, err := get(client); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+		if _, err := client.Do(request); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected to be rate limited, got %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("expected the exhausted host not to be contacted, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("opens the breaker", func(t *testing.T) {
+		failures := make([]int, 10)
+		noHeaders := make([]http.Header, 10)
+		for i := range failures {
+			failures[i], noHeaders[i] = 503, http.Header{}
+		}
+		reset(failures, noHeaders...)
+
+		now := time.Now()
+		breakers := NewBreakers(BreakerPolicy{Window: 4, MinRequests: 4, FailureRatio: 0.5, Cooldown: time.Minute})
+		breakers.now = func() time.Time { return now }
+		client := newClient(breakers)
+		client.Transport.(*retryTransport).policy.Attempts = 1
+
+		for i := 0; i < 4; i++ {
+			get(client)
+		}
+		if state := breakers.State(server.Listener.Addr().String()); state != BreakerOpen {
+			t.Fatalf("expected the breaker to be open, got %v", state)
+		}
+
+		if _, err := get(client); !errors.Is(err, ErrCircuitOpen) || calls.Load() != 4 {
+			t.Errorf("expected to fail fast, got %v after %d calls", err, calls.Load())
+		}
+
+		now = now.Add(time.Minute)
+		statuses = nil
+		if response, err := get(client); err != nil || response.StatusCode != http.StatusOK {
+			t.Fatalf("expected the probe to succeed, got %v %v", response, err)
+		}
+		if state := breakers.State(server.Listener.Addr().String()); state != BreakerClosed {
+			t.Errorf("expected the breaker to close, got %v", state)
+		}
+
+		status := breakers.Snapshot()[0]
+		if status.Requests != 5 || status.Failures != 4 || status.Rejected != 1 {
+			t.Errorf("unexpected status: %+v", status)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package gazetteer
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type City struct {
+	Name       string
+	Country    string
+	Latitude   float64
+	Longitude  float64
+	Timezone   string
+	Population int
+}
+
+//go:embed cities.csv
+var citiesCSV string
+
+var cities = mustParse(citiesCSV)
+
+func mustParse(s string) []City {
+	records, err := csv.NewReader(strings.NewReader(s)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("error reading bundled gazetteer: %v", err))
+	}
+
+	parsed := make([]City, 0, len(records))
+	for i, record := range records[1:] {
+		lat, errLat := strconv.ParseFloat(record[2], 64)
+		lon, errLon := strconv.ParseFloat(record[3], 64)
+		pop, errPop := strconv.Atoi(record[5])
+		if errLat != nil || errLon != nil || errPop != nil {
+			panic(fmt.Sprintf("invalid bundled gazetteer entry on line %d", i+2))
+		}
+
+		parsed = append(parsed, City{
+			Name:       record[0],
+			Country:    record[1],
+			Latitude:   lat,
+			Longitude:  lon,
+			Timezone:   record[4],
+			Population: pop,
+		})
+	}
+
+	return parsed
+}
+
+// Search finds the most populous city whose name matches query. Exact
+// matches win over prefix matches, and an optional ", country" suffix
+// narrows the search.
+func Search(query string) (City, bool) {
+	name, country, _ := strings.Cut(query, ",")
+	name = strings.TrimSpace(name)
+	country = strings.TrimSpace(country)
+	if name == "" {
+		return City{}, false
+	}
+
+	var best City
+	bestRank := 0
+	for _, city := range cities {
+		if country != "" && !strings.EqualFold(city.Country, country) {
+			continue
+		}
+
+		rank := 0
+		switch {
+		case strings.EqualFold(city.Name, name):
+			rank = 2
+		case len(city.Name) >= len(name) && strings.EqualFold(city.Name[:len(name)], name):
+			rank = 1
+		default:
+			continue
+		}
+
+		if rank > bestRank || (rank == bestRank && city.Population > best.Population) {
+			best = city
+			bestRank = rank
+		}
+	}
+
+	return best, bestRank > 0
+}
+
+// Names lists every bundled city, for search suggestions.
+func Names() []string {
+	names := make([]string, len(cities))
+	for i, city := range cities {
+		names[i] = city.Name
+	}
+
+	return names
+}
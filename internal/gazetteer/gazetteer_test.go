@@ -0,0 +1,33 @@
+package gazetteer
+
+import "testing"
+
+func TestSearch(t *testing.T) {
+	cases := map[string]string{
+		"london":                "London",
+		"  Paris ":              "Paris",
+		"san f":                 "San Francisco",
+		"san":                   "Santiago",
+		"Sydney, Australia":     "Sydney",
+		"Reykjavík":             "Reykjavík",
+		"Wellington, australia": "",
+		"Atlantis":              "",
+		"":                      "",
+	}
+
+	for query, want := range cases {
+		t.Run(query, func(t *testing.T) {
+			city, ok := Search(query)
+			if want == "" {
+				if ok {
+					t.Errorf("expected no match, got %+v", city)
+				}
+				return
+			}
+
+			if !ok || city.Name != want {
+				t.Errorf("expected %s, got %+v", want, city)
+			}
+		})
+	}
+}
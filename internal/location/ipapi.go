@@ -1,15 +1,20 @@
 package location
 
 import (
-	"fmt"
-	"net/url"
 	"weather/internal/fetch"
 	"weather/internal/validation"
+
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
 )
 
 type IPAPIGeolocation struct {
 	Query       string  `json:"query"`
 	Status      string  `json:"status"`
+	Message     string  `json:"message"`
 	Country     string  `json:"country"`
 	CountryCode string  `json:"countryCode"`
 	Region      string  `json:"region"`
@@ -22,28 +27,68 @@ type IPAPIGeolocation struct {
 }
 
 func (loc IPAPIGeolocation) Validate() (validation.ValidationProblems, error) {
-	// TODO
-	return nil, nil
+	// Failed lookups, like those for private and reserved ranges, come back
+	// with every other field zeroed.
+	v := validation.Validator{}
+
+	if !validation.Field(&v, "status", loc.Status, validation.OneOf("success")) {
+		v.Problem("message", "%s", loc.Message)
+		return v.Validate()
+	}
+
+	validation.Field(&v, "lat", loc.Lat, validation.Between(-90.0, 90.0))
+	validation.Field(&v, "lon", loc.Lon, validation.Between(-180.0, 180.0))
+	validation.Field(&v, "timezone", loc.Timezone, validation.Timezone)
+
+	return v.Validate()
 }
 
-const basePath = "http://ip-api.com/json/"
+const IPAPIBasePath = "http://ip-api.com/json/"
 const fields = "status,message,country,countryCode,region,regionName,city,zip,lat,lon,timezone,query"
-const defaultIP = "127.0.0.1"
 
-func ForIP(ip string) (IPAPIGeolocation, error) {
+// IPAPI is a Provider backed by ip-api.com.
+type IPAPI struct {
+	BasePath string
+	Client   *http.Client
+}
+
+func (p IPAPI) ForIP(ctx context.Context, ip string) (Geolocation, error) {
+	loc, err := forIP(ctx, p.Client, p.BasePath, ip)
+	if err != nil {
+		if loc.Status == "fail" {
+			return Geolocation{}, fmt.Errorf("%w: %s: %s", ErrNotFound, ip, loc.Message)
+		}
+		return Geolocation{}, err
+	}
+
+	return Geolocation{
+		Lat:      loc.Lat,
+		Lon:      loc.Lon,
+		City:     loc.City,
+		Country:  loc.Country,
+		Timezone: loc.Timezone,
+	}, nil
+}
+
+func ForIP(ctx context.Context, ip string) (IPAPIGeolocation, error) {
+	return forIP(ctx, nil, IPAPIBasePath, ip)
+}
+
+func forIP(ctx context.Context, client *http.Client, base string, ip string) (IPAPIGeolocation, error) {
 	geolocation := IPAPIGeolocation{}
 
-	endpoint := basePath
-	if ip == defaultIP {
+	// ip-api can't locate loopback addresses, but given no address at all it
+	// locates the requester, which is us.
+	if addr, err := netip.ParseAddr(ip); err == nil && addr.IsLoopback() {
 		ip = ""
 	}
 
-	endpoint, err := url.JoinPath(basePath, ip)
+	endpoint, err := url.JoinPath(base, ip)
 	if err != nil {
 		return geolocation, fmt.Errorf("error building IP-API path for ip: %s: %w", ip, err)
 	}
 
-	if err := fetch.JSON(endpoint, &geolocation); err != nil {
+	if err := fetch.JSON(ctx, client, endpoint, &geolocation); err != nil {
 		return geolocation, fmt.Errorf("error communicating with IP-API.com, %w", err)
 	}
 
@@ -1,6 +1,13 @@
 package location
 
 import (
+	"weather/internal/fetch"
+	"weather/internal/validation"
+
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -8,8 +15,43 @@ func TestForIP(t *testing.T) {
 	const ip string = "24.48.0.1"
 
 	t.Run("runs successfully", func(t *testing.T) {
-		if _, err := ForIP(ip); err != nil {
+		if _, err := ForIP(context.Background(), ip); err != nil {
 			t.Errorf("%v", err.Error())
 		}
 	})
 }
+
+func TestIPAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/10.0.0.1":
+			w.Write([]byte(`{"status": "fail", "message": "private range", "query": "10.0.0.1"}`))
+		default:
+			w.Write([]byte(`{"status": "success", "lat": 45.5, "lon": 200, "timezone": "Mars/Olympus_Mons"}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := IPAPI{BasePath: server.URL}
+
+	t.Run("treats failed lookups as not found", func(t *testing.T) {
+		if _, err := provider.ForIP(context.Background(), "10.0.0.1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("surfaces problems", func(t *testing.T) {
+		_, err := provider.ForIP(context.Background(), "24.48.0.1")
+
+		invalid := &fetch.ValidationError{}
+		if !errors.As(err, &invalid) {
+			t.Fatalf("expected a ValidationError, got %v", err)
+		}
+		if len(invalid.Problems) != 2 || invalid.Problems["lon"] == "" || invalid.Problems["timezone"] == "" {
+			t.Errorf("unexpected problems: %v", invalid.Problems)
+		}
+		if errors.Is(err, validation.ErrValidation) {
+			t.Errorf("expected the upstream's problems not to pass for the caller's, got %v", err)
+		}
+	})
+}
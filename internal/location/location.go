@@ -0,0 +1,107 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Geolocation is where an IP address is, normalized across providers.
+type Geolocation struct {
+	Lat      float64
+	Lon      float64
+	City     string
+	Country  string
+	Timezone string
+}
+
+// Provider looks up the location of an IP address.
+type Provider interface {
+	ForIP(ctx context.Context, ip string) (Geolocation, error)
+}
+
+var ErrNotFound = errors.New("no location found for IP")
+
+// Chain is an ordered list of providers. Each is tried in turn until one
+// finds the IP.
+type Chain []Provider
+
+func (c Chain) ForIP(ctx context.Context, ip string) (Geolocation, error) {
+	var errs []error
+	for _, provider := range c {
+		loc, err := provider.ForIP(ctx, ip)
+		if err == nil {
+			return loc, nil
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return loc, ctxErr
+		}
+
+		log.Printf("location provider %T failed, trying next: %v", provider, err)
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return Geolocation{}, errors.New("no location providers configured")
+	}
+
+	return Geolocation{}, errors.Join(errs...)
+}
+
+// Close releases any providers that hold resources, such as open MMDB
+// files.
+func (c Chain) Close() error {
+	var errs []error
+	for _, provider := range c {
+		if closer, ok := provider.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Options configures the providers built by NewChain.
+type Options struct {
+	IPAPIBasePath string
+	MMDBPath      string
+
+	// Client makes outbound requests, fetch.DefaultClient when nil.
+	Client *http.Client
+}
+
+// NewChain builds a Chain from provider names, in order.
+func NewChain(names []string, opts Options) (Chain, error) {
+	chain := Chain{}
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case "ipapi":
+			chain = append(chain, IPAPI{BasePath: opts.IPAPIBasePath, Client: opts.Client})
+		case "mmdb":
+			if opts.MMDBPath == "" {
+				return nil, errors.New("the mmdb location provider needs a database path")
+			}
+
+			db, err := OpenMMDB(opts.MMDBPath)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, db)
+		default:
+			return nil, fmt.Errorf("unknown location provider: %q", name)
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("no location providers configured")
+	}
+
+	return chain, nil
+}
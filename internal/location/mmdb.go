@@ -0,0 +1,69 @@
+package location
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// mmdbRecord is the subset of a GeoLite2-City record we use.
+type mmdbRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+		TimeZone  string   `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+}
+
+// MMDB is a Provider backed by a local MaxMind GeoLite2-City format
+// database, so lookups never leave the machine.
+type MMDB struct {
+	reader *maxminddb.Reader
+}
+
+func OpenMMDB(path string) (*MMDB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening MMDB database %s: %w", path, err)
+	}
+
+	return &MMDB{reader: reader}, nil
+}
+
+func (db *MMDB) ForIP(ctx context.Context, ip string) (Geolocation, error) {
+	_ = ctx
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Geolocation{}, fmt.Errorf("invalid IP address: %q", ip)
+	}
+
+	record := mmdbRecord{}
+	if err := db.reader.Lookup(addr, &record); err != nil {
+		return Geolocation{}, fmt.Errorf("error reading MMDB record for %s: %w", ip, err)
+	}
+
+	if record.Location.Latitude == nil || record.Location.Longitude == nil {
+		return Geolocation{}, fmt.Errorf("%w: %s", ErrNotFound, ip)
+	}
+
+	return Geolocation{
+		Lat:      *record.Location.Latitude,
+		Lon:      *record.Location.Longitude,
+		City:     record.City.Names["en"],
+		Country:  record.Country.Names["en"],
+		Timezone: record.Location.TimeZone,
+	}, nil
+}
+
+func (db *MMDB) Close() error {
+	return db.reader.Close()
+}
@@ -0,0 +1,119 @@
+package location
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The helpers below hand-encode just enough of the MaxMind DB format to
+// build a tiny IPv4 database for tests.
+//
+// https://maxmind.github.io/MaxMind-DB/
+
+func mmdbString(s string) []byte {
+	return append([]byte{2<<5 | byte(len(s))}, s...)
+}
+
+func mmdbDouble(f float64) []byte {
+	return binary.BigEndian.AppendUint64([]byte{3<<5 | 8}, math.Float64bits(f))
+}
+
+func mmdbUint16(n uint16) []byte {
+	return binary.BigEndian.AppendUint16([]byte{5<<5 | 2}, n)
+}
+
+func mmdbUint32(n uint32) []byte {
+	return binary.BigEndian.AppendUint32([]byte{6<<5 | 4}, n)
+}
+
+func mmdbMap(pairs ...[]byte) []byte {
+	b := []byte{7<<5 | byte(len(pairs)/2)}
+	for _, p := range pairs {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// writeTestMMDB writes a database where network/prefix maps to record.
+func writeTestMMDB(t *testing.T, network [4]byte, prefix int, record []byte) string {
+	t.Helper()
+
+	nodeCount := uint32(prefix)
+	tree := []byte{}
+	for i := 0; i < prefix; i++ {
+		bit := (network[i/8] >> (7 - i%8)) & 1
+
+		next := uint32(i + 1)
+		if i == prefix-1 {
+			next = nodeCount + 16 // points at the start of the data section
+		}
+
+		records := [2]uint32{nodeCount, nodeCount}
+		records[bit] = next
+		for _, r := range records {
+			tree = append(tree, byte(r>>16), byte(r>>8), byte(r))
+		}
+	}
+
+	db := append(tree, make([]byte, 16)...)
+	db = append(db, record...)
+	db = append(db, "\xab\xcd\xefMaxMind.com"...)
+	db = append(db, mmdbMap(
+		mmdbString("node_count"), mmdbUint32(nodeCount),
+		mmdbString("record_size"), mmdbUint16(24),
+		mmdbString("ip_version"), mmdbUint16(4),
+		mmdbString("database_type"), mmdbString("Test-City"),
+		mmdbString("binary_format_major_version"), mmdbUint16(2),
+		mmdbString("binary_format_minor_version"), mmdbUint16(0),
+	)...)
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, db, 0o600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return path
+}
+
+func TestMMDB(t *testing.T) {
+	names := func(en string) []byte { return mmdbMap(mmdbString("names"), mmdbMap(mmdbString("en"), mmdbString(en))) }
+
+	path := writeTestMMDB(t, [4]byte{24, 48, 0, 0}, 16, mmdbMap(
+		mmdbString("city"), names("Montreal"),
+		mmdbString("country"), names("Canada"),
+		mmdbString("location"), mmdbMap(
+			mmdbString("latitude"), mmdbDouble(45.5),
+			mmdbString("longitude"), mmdbDouble(-73.6),
+			mmdbString("time_zone"), mmdbString("America/Toronto"),
+		),
+	))
+
+	db, err := OpenMMDB(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer db.Close()
+
+	t.Run("finds known networks", func(t *testing.T) {
+		loc, err := db.ForIP(context.Background(), "24.48.0.1")
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		want := Geolocation{Lat: 45.5, Lon: -73.6, City: "Montreal", Country: "Canada", Timezone: "America/Toronto"}
+		if loc != want {
+			t.Errorf("expected %+v, got %+v", want, loc)
+		}
+	})
+
+	t.Run("reports unknown networks", func(t *testing.T) {
+		if _, err := db.ForIP(context.Background(), "10.0.0.1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
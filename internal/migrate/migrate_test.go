@@ -0,0 +1,89 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestUp(t *testing.T) {
+	ctx := context.Background()
+
+	migrations, err := Load(fstest.MapFS{
+		"0002_add_column.sql": {Data: []byte("ALTER TABLE things ADD COLUMN name TEXT;")},
+		"0001_create.sql":     {Data: []byte("CREATE TABLE things (id INTEGER PRIMARY KEY);")},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	db := openTestDB(t)
+
+	t.Run("applies pending migrations in order", func(t *testing.T) {
+		done, err := Up(ctx, db, migrations)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(done) != 2 || done[0].Version != 1 || done[1].Version != 2 {
+			t.Errorf("unexpected migrations applied: %+v", done)
+		}
+	})
+
+	t.Run("skips applied migrations", func(t *testing.T) {
+		done, err := Up(ctx, db, migrations)
+		if err != nil || len(done) != 0 {
+			t.Errorf("expected nothing to apply, got %+v (%v)", done, err)
+		}
+	})
+
+	t.Run("rolls back failed migrations", func(t *testing.T) {
+		broken := append(migrations, Migration{
+			Version: 3,
+			Name:    "broken",
+			SQL:     "CREATE TABLE others (id INTEGER); INSERT INTO nowhere VALUES (1);",
+		})
+
+		if _, err := Up(ctx, db, broken); err == nil {
+			t.Fatalf("expected an error")
+		}
+
+		statuses, err := Statuses(ctx, db, broken)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if statuses[2].AppliedAt != nil {
+			t.Errorf("expected migration 3 to be pending")
+		}
+
+		if _, err := db.Exec("SELECT * FROM others"); err == nil {
+			t.Errorf("expected table from failed migration to be rolled back")
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("rejects duplicate versions", func(t *testing.T) {
+		if _, err := Load(fstest.MapFS{
+			"0001_a.sql": {Data: []byte("")},
+			"1_b.sql":    {Data: []byte("")},
+		}); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -2,25 +2,117 @@ package observation
 
 import (
 	"weather/internal/data"
+	"weather/internal/weather"
 
 	"context"
-	"time"
+	"fmt"
+	"math"
 )
 
-func ResolvePriorObservation(ctx context.Context, obs data.Observation, db *data.Queries) (*data.Observation, error) {
-	_ = ctx
-	return &data.Observation{
-		ID:               0,
-		Latitude:         0.00,
-		Longitude:        0.00,
-		Timezone:         "GMT",
-		TempC:            0.0,
-		TempF:            32.0,
-		RelativeHumidity: 0.0,
-		Rain:             0.0,
-		Snowfall:         0.0,
-		WeatherCode:      "2",
-		TimeUtc:          time.Now().UTC(),
-		TimeLocal:        time.Now().UTC(),
+// PriorObservation is an earlier visitor's observation along with what they
+// drew for it.
+type PriorObservation struct {
+	Observation data.Observation
+	Drawing     data.ObservationDrawing
+}
+
+// candidateLimit bounds how many recent drawn observations are scored.
+const candidateLimit = 500
+
+const (
+	codeMismatchWeight     = 3.0
+	categoryMismatchWeight = 1.0
+	tempWeightPerC         = 1.0 / 10.0
+	precipWeightPerMM      = 1.0 / 5.0
+	distanceWeightPerKM    = 1.0 / 5000.0
+)
+
+// ResolvePriorObservation picks the drawn observation from another visitor
+// whose conditions best match obs. It returns nil when nothing has been drawn
+// yet.
+func ResolvePriorObservation(ctx context.Context, obs data.Observation, db *data.Queries) (*PriorObservation, error) {
+	candidates, err := db.PriorObservationCandidates(ctx, data.PriorObservationCandidatesParams{
+		ID:    obs.ID,
+		Limit: candidateLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading prior observation candidates: %w", err)
+	}
+
+	best := bestMatch(obs, candidates)
+	if best == nil {
+		return nil, nil
+	}
+
+	return &PriorObservation{
+		Observation: best.Observation,
+		Drawing:     best.ObservationDrawing,
 	}, nil
 }
+
+// bestMatch returns the lowest scoring candidate, breaking ties in favor of
+// the most recent observation whatever order the candidates are in.
+func bestMatch(obs data.Observation, candidates []data.PriorObservationCandidatesRow) *data.PriorObservationCandidatesRow {
+	var best *data.PriorObservationCandidatesRow
+	bestScore := math.Inf(1)
+
+	for i := range candidates {
+		if candidates[i].Observation.ID == obs.ID {
+			continue
+		}
+
+		s := score(obs, candidates[i].Observation)
+		if s < bestScore || s == bestScore && candidates[i].Observation.TimeUtc.After(best.Observation.TimeUtc) {
+			best = &candidates[i]
+			bestScore = s
+		}
+	}
+
+	return best
+}
+
+// score measures how different two observations are; lower is closer.
+func score(a data.Observation, b data.Observation) float64 {
+	s := 0.0
+
+	switch {
+	case !a.WeatherCode.Valid() || !b.WeatherCode.Valid():
+		s += codeMismatchWeight
+	case a.WeatherCode == b.WeatherCode:
+		break
+	case sameCategory(a.WeatherCode, b.WeatherCode):
+		s += categoryMismatchWeight
+	default:
+		s += codeMismatchWeight
+	}
+
+	s += math.Abs(a.TempC-b.TempC) * tempWeightPerC
+	s += math.Abs(a.Rain-b.Rain) * precipWeightPerMM
+	s += math.Abs(a.Snowfall-b.Snowfall) * precipWeightPerMM
+	s += distanceKM(a.Latitude, a.Longitude, b.Latitude, b.Longitude) * distanceWeightPerKM
+
+	return s
+}
+
+// sameCategory reports whether two weather codes are in the same
+// weather.Category.
+func sameCategory(a weather.Code, b weather.Code) bool {
+	categoryA, okA := a.Category()
+	categoryB, okB := b.Category()
+	return okA && okB && categoryA == categoryB
+}
+
+const earthRadiusKM = 6371.0
+
+// distanceKM is the haversine distance between two coordinates.
+func distanceKM(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKM * math.Asin(math.Sqrt(h))
+}
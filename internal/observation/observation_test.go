@@ -0,0 +1,60 @@
+package observation
+
+import (
+	"weather/internal/data"
+	"weather/internal/weather"
+
+	"testing"
+	"time"
+)
+
+func candidate(id int64, code weather.Code, tempC float64, age time.Duration) data.PriorObservationCandidatesRow {
+	return data.PriorObservationCandidatesRow{
+		Observation: data.Observation{
+			ID:          id,
+			Latitude:    40.0,
+			Longitude:   -75.0,
+			TempC:       tempC,
+			WeatherCode: code,
+			TimeUtc:     time.Now().Add(-age),
+		},
+		ObservationDrawing: data.ObservationDrawing{ObservationID: id},
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	current := data.Observation{ID: 1, Latitude: 40.0, Longitude: -75.0, TempC: 20, WeatherCode: 63}
+
+	t.Run("prefers matching conditions", func(t *testing.T) {
+		best := bestMatch(current, []data.PriorObservationCandidatesRow{
+			candidate(2, 0, 20, time.Minute),
+			candidate(3, 61, 19, time.Hour),
+			candidate(4, 63, 35, time.Hour),
+		})
+		if best == nil || best.Observation.ID != 3 {
+			t.Errorf("expected observation 3, got %+v", best)
+		}
+	})
+
+	t.Run("excludes the current observation", func(t *testing.T) {
+		best := bestMatch(current, []data.PriorObservationCandidatesRow{
+			candidate(1, 63, 20, 0),
+		})
+		if best != nil {
+			t.Errorf("expected no match, got %+v", best)
+		}
+	})
+
+	t.Run("breaks ties by recency", func(t *testing.T) {
+		// The older candidate comes first, so only the scorer can pick the
+		// newer one.
+		best := bestMatch(current, []data.PriorObservationCandidatesRow{
+			candidate(6, 63, 20, time.Hour),
+			candidate(5, 63, 20, time.Minute),
+			candidate(7, 63, 20, 2*time.Hour),
+		})
+		if best == nil || best.Observation.ID != 5 {
+			t.Errorf("expected observation 5, got %+v", best)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"weather/internal/data"
+	"weather/internal/drawing"
+
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+type drawingRepository struct {
+	db      *data.Queries
+	palette drawing.Palette
+	hash    string
+}
+
+// NewDrawingRepository renders drawings with palette. Rendered copies are
+// stored under the palette's hash, so reconfiguring it renders them again.
+func NewDrawingRepository(db *data.Queries, palette drawing.Palette) DrawingRepository {
+	return &drawingRepository{db: db, palette: palette, hash: palette.Hash()}
+}
+
+func (r *drawingRepository) ForObservation(ctx context.Context, observationID int64) (data.ObservationDrawing, error) {
+	stored, err := r.db.GetObservationDrawing(ctx, observationID)
+	if err != nil {
+		return stored, fmt.Errorf("error reading drawing for observation %v: %w", observationID, data.Translate(err))
+	}
+
+	return stored, nil
+}
+
+func (r *drawingRepository) Add(ctx context.Context, stored data.ObservationDrawing) error {
+	return data.Translate(r.db.AddObservationDrawing(ctx, data.AddObservationDrawingParams{
+		ObservationID: stored.ObservationID,
+		Data:          stored.Data,
+		SizeBytes:     stored.SizeBytes,
+		TimeSubmitted: stored.TimeSubmitted,
+	}))
+}
+
+// PNG reuses the copy stored in SQLite when the drawing has already been
+// rendered with the current palette.
+func (r *drawingRepository) PNG(ctx context.Context, observationID int64) ([]byte, error) {
+	image, err := r.db.GetObservationDrawingImage(ctx, data.GetObservationDrawingImageParams{
+		ObservationID: observationID,
+		Palette:       r.hash,
+	})
+	switch err = data.Translate(err); {
+	case err == nil:
+		return image.Png, nil
+	case errors.Is(err, ErrNotFound):
+		break
+	default:
+		return nil, fmt.Errorf("error reading rendered drawing for observation %v: %w", observationID, err)
+	}
+
+	stored, err := r.ForObservation(ctx, observationID)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := drawing.ParseStored(stored.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding drawing for observation %v: %w", observationID, err)
+	}
+
+	png, err := drawing.RenderPNG(decoded, r.palette)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering drawing for observation %v: %w", observationID, err)
+	}
+
+	if err := r.db.AddObservationDrawingImage(ctx, data.AddObservationDrawingImageParams{
+		ObservationID: observationID,
+		Palette:       r.hash,
+		Png:           png,
+		TimeRendered:  time.Now().UTC(),
+	}); err != nil {
+		log.Printf("error storing rendered drawing for observation %v: %v", observationID, err)
+	}
+
+	if err := r.db.DeleteStaleObservationDrawingImages(ctx, data.DeleteStaleObservationDrawingImagesParams{
+		ObservationID: observationID,
+		Palette:       r.hash,
+	}); err != nil {
+		log.Printf("error deleting stale renders of drawing for observation %v: %v", observationID, err)
+	}
+
+	return png, nil
+}
+
+func (r *drawingRepository) List(ctx context.Context, params data.ListDrawnObservationsParams) ([]data.ListDrawnObservationsRow, error) {
+	rows, err := r.db.ListDrawnObservations(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("error listing drawn observations: %w", err)
+	}
+
+	return rows, nil
+}
+
+func (r *drawingRepository) CountByCategory(ctx context.Context) ([]data.CountDrawnObservationsByCategoryRow, error) {
+	rows, err := r.db.CountDrawnObservationsByCategory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error counting drawn observations: %w", err)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"weather/internal/data"
+	"weather/internal/drawing"
+
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrawingRepository(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := openTestDB(t)
+	db := data.New(sqlDB)
+
+	obs, err := db.UpsertObservation(ctx, data.UpsertObservationParams{
+		Timezone:  "UTC",
+		TimeUtc:   time.Now().UTC(),
+		TimeLocal: time.Now().UTC(),
+		BucketUtc: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	drawings := NewDrawingRepository(db, drawing.DefaultPalette)
+	if err := drawings.Add(ctx, data.ObservationDrawing{
+		ObservationID: obs.ID,
+		Data:          "AQH0AfQAAQACAAEAAgADAAQ=",
+		SizeBytes:     24,
+		TimeSubmitted: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	t.Run("can't find missing drawings", func(t *testing.T) {
+		if _, err := drawings.PNG(ctx, obs.ID+1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("renders again when the palette changes", func(t *testing.T) {
+		first, err := drawings.PNG(ctx, obs.ID)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		repainted := NewDrawingRepository(db, drawing.Palette{
+			Colors:     []string{"#000000", "#ffffff"},
+			BrushSizes: []int{5},
+		})
+		second, err := repainted.PNG(ctx, obs.ID)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if bytes.Equal(first, second) {
+			t.Errorf("expected the new palette to render a different image")
+		}
+
+		var images int
+		if err := sqlDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM observation_drawing_images").Scan(&images); err != nil || images != 1 {
+			t.Errorf("expected only the current render to be kept, got %d (%v)", images, err)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"weather/internal/cache"
+	"weather/internal/data"
+	"weather/internal/weather"
+
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// forecastDateLayout is how forecast days are stored.
+const forecastDateLayout = time.DateOnly
+
+type forecastRepository struct {
+	sqlDB    TxDB
+	db       *data.Queries
+	provider weather.ForecastProvider
+	ttl      time.Duration
+	cache    *cache.TTL[latLon, weather.Forecast]
+}
+
+// NewForecastRepository stores forecasts in sqlDB, which it needs rather than
+// just queries so each snapshot is written in one transaction.
+func NewForecastRepository(sqlDB TxDB, provider weather.ForecastProvider, ttl time.Duration) ForecastRepository {
+	return &forecastRepository{
+		sqlDB:    sqlDB,
+		db:       data.New(sqlDB),
+		provider: provider,
+		ttl:      ttl,
+		cache:    cache.NewTTL[latLon, weather.Forecast](ttl),
+	}
+}
+
+func (r *forecastRepository) ForLocation(ctx context.Context, loc data.Geolocation) (weather.Forecast, error) {
+	key := keyFor(loc.Latitude, loc.Longitude)
+	if forecast, ok := r.cache.Get(key); ok {
+		return forecast, nil
+	}
+
+	stored, err := r.db.GetRecentForecast(ctx, data.GetRecentForecastParams{
+		CellLat:       key.lat,
+		CellLon:       key.lon,
+		TimeGenerated: time.Now().UTC().Add(-r.ttl),
+	})
+	switch {
+	case err == nil:
+		forecast, err := r.read(ctx, stored)
+		if err != nil {
+			return forecast, err
+		}
+
+		r.cache.Set(key, forecast)
+		return forecast, nil
+	case errors.Is(err, sql.ErrNoRows):
+		break
+	default:
+		return weather.Forecast{}, fmt.Errorf("error reading recent forecast: %w", err)
+	}
+
+	// Like observations, the forecast is for the whole cell, not whichever
+	// visitor in it asked first.
+	lat, lon := key.center()
+	forecast, err := r.provider.Forecast(ctx, lat, lon)
+	if err != nil {
+		return forecast, err
+	}
+
+	if err := r.store(ctx, key, forecast); err != nil {
+		return forecast, err
+	}
+
+	r.cache.Set(key, forecast)
+	return forecast, nil
+}
+
+// read loads the hours and days of a stored forecast.
+func (r *forecastRepository) read(ctx context.Context, stored data.Forecast) (weather.Forecast, error) {
+	tzloc, err := time.LoadLocation(stored.Timezone)
+	if err != nil {
+		tzloc = time.UTC
+	}
+
+	hours, err := r.db.ListForecastHours(ctx, stored.ID)
+	if err != nil {
+		return weather.Forecast{}, fmt.Errorf("error reading hours of forecast %v: %w", stored.ID, err)
+	}
+
+	days, err := r.db.ListForecastDays(ctx, stored.ID)
+	if err != nil {
+		return weather.Forecast{}, fmt.Errorf("error reading days of forecast %v: %w", stored.ID, err)
+	}
+
+	forecast := weather.Forecast{
+		Timezone: stored.Timezone,
+		Hourly:   make([]weather.HourlyForecast, len(hours)),
+		Daily:    make([]weather.DailyForecast, len(days)),
+	}
+
+	for i, hour := range hours {
+		forecast.Hourly[i] = weather.HourlyForecast{
+			Time:                     hour.TimeUtc.In(tzloc),
+			TemperatureC:             hour.TempC,
+			PrecipitationProbability: int(hour.PrecipitationProbability),
+			WeatherCode:              weather.Code(hour.WeatherCode),
+		}
+	}
+
+	for i, day := range days {
+		date, err := time.ParseInLocation(forecastDateLayout, day.Date, tzloc)
+		if err != nil {
+			return weather.Forecast{}, fmt.Errorf("error reading date of forecast %v: %w", stored.ID, err)
+		}
+
+		forecast.Daily[i] = weather.DailyForecast{
+			Date:                        date,
+			TemperatureMinC:             day.TempMinC,
+			TemperatureMaxC:             day.TempMaxC,
+			PrecipitationProbabilityMax: int(day.PrecipitationProbabilityMax),
+			WeatherCode:                 weather.Code(day.WeatherCode),
+			Sunrise:                     day.SunriseUtc.In(tzloc),
+			Sunset:                      day.SunsetUtc.In(tzloc),
+		}
+	}
+
+	return forecast, nil
+}
+
+// store saves a snapshot of forecast for the cell at key, replacing any
+// earlier one.
+func (r *forecastRepository) store(ctx context.Context, key latLon, forecast weather.Forecast) error {
+	tx, err := r.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error storing forecast: %w", err)
+	}
+	defer tx.Rollback()
+
+	db := r.db.WithTx(tx)
+
+	lat, lon := key.center()
+	stored, err := db.AddForecast(ctx, data.AddForecastParams{
+		Latitude:      lat,
+		Longitude:     lon,
+		CellLat:       key.lat,
+		CellLon:       key.lon,
+		Timezone:      forecast.Timezone,
+		TimeGenerated: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("error storing forecast: %w", err)
+	}
+
+	for _, hour := range forecast.Hourly {
+		if err := db.AddForecastHour(ctx, data.AddForecastHourParams{
+			ForecastID:               stored.ID,
+			TimeUtc:                  hour.Time.UTC(),
+			TempC:                    hour.TemperatureC,
+			PrecipitationProbability: int64(hour.PrecipitationProbability),
+			WeatherCode:              int64(hour.WeatherCode),
+		}); err != nil {
+			return fmt.Errorf("error storing hours of forecast %v: %w", stored.ID, err)
+		}
+	}
+
+	for _, day := range forecast.Daily {
+		if err := db.AddForecastDay(ctx, data.AddForecastDayParams{
+			ForecastID:                  stored.ID,
+			Date:                        day.Date.Format(forecastDateLayout),
+			TempMinC:                    day.TemperatureMinC,
+			TempMaxC:                    day.TemperatureMaxC,
+			PrecipitationProbabilityMax: int64(day.PrecipitationProbabilityMax),
+			WeatherCode:                 int64(day.WeatherCode),
+			SunriseUtc:                  day.Sunrise.UTC(),
+			SunsetUtc:                   day.Sunset.UTC(),
+		}); err != nil {
+			return fmt.Errorf("error storing days of forecast %v: %w", stored.ID, err)
+		}
+	}
+
+	if err := db.DeleteSupersededForecasts(ctx, data.DeleteSupersededForecastsParams{
+		CellLat: key.lat,
+		CellLon: key.lon,
+		ID:      stored.ID,
+	}); err != nil {
+		return fmt.Errorf("error deleting forecasts superseded by %v: %w", stored.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error storing forecast %v: %w", stored.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"weather/internal/data"
+	"weather/internal/weather"
+
+	"context"
+	"testing"
+	"time"
+)
+
+// fixedForecaster forecasts the same hour and day everywhere, recording
+// where it was asked for.
+type fixedForecaster struct {
+	asked [][2]float64
+}
+
+func (p *fixedForecaster) Forecast(ctx context.Context, lat float64, lon float64) (weather.Forecast, error) {
+	p.asked = append(p.asked, [2]float64{lat, lon})
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	return weather.Forecast{
+		Timezone: "UTC",
+		Hourly:   []weather.HourlyForecast{{Time: start, TemperatureC: 9, WeatherCode: 3}},
+		Daily:    []weather.DailyForecast{{Date: start, TemperatureMinC: 4, TemperatureMaxC: 14, Sunrise: start, Sunset: start}},
+	}, nil
+}
+
+func TestForecastRepository(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := openTestDB(t)
+	provider := &fixedForecaster{}
+
+	count := func(table string) int {
+		t.Helper()
+
+		var n int
+		if err := sqlDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n); err != nil {
+			t.Fatalf("%v", err)
+		}
+		return n
+	}
+
+	first := data.Geolocation{Latitude: 52.521, Longitude: 13.419}
+	neighbor := data.Geolocation{Latitude: 52.519, Longitude: 13.421}
+
+	t.Run("forecasts the cell's center", func(t *testing.T) {
+		forecasts := NewForecastRepository(sqlDB, provider, time.Hour)
+		if _, err := forecasts.ForLocation(ctx, first); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if len(provider.asked) != 1 || provider.asked[0] != [2]float64{52.52, 13.42} {
+			t.Errorf("expected one forecast at the cell's center, got %v", provider.asked)
+		}
+	})
+
+	t.Run("shares the stored forecast with the cell", func(t *testing.T) {
+		// A new repository has nothing cached, so this comes from SQLite.
+		forecasts := NewForecastRepository(sqlDB, provider, time.Hour)
+		forecast, err := forecasts.ForLocation(ctx, neighbor)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if len(provider.asked) != 1 {
+			t.Errorf("expected the neighbor to reuse the forecast, got %d upstream calls", len(provider.asked))
+		}
+		if len(forecast.Hourly) != 1 || forecast.Hourly[0].TemperatureC != 9 {
+			t.Errorf("unexpected forecast %+v", forecast)
+		}
+	})
+
+	t.Run("replaces superseded forecasts", func(t *testing.T) {
+		// Nothing is recent enough to reuse without a TTL.
+		forecasts := NewForecastRepository(sqlDB, provider, 0)
+		if _, err := forecasts.ForLocation(ctx, neighbor); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if len(provider.asked) != 2 {
+			t.Errorf("expected a second upstream call, got %d", len(provider.asked))
+		}
+		for _, table := range []string{"forecasts", "forecast_hours", "forecast_days"} {
+			if n := count(table); n != 1 {
+				t.Errorf("expected only the newest snapshot in %s, got %d rows", table, n)
+			}
+		}
+	})
+}
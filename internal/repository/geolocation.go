@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"weather/internal/cache"
+	"weather/internal/data"
+	"weather/internal/location"
+
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+type geolocationRepository struct {
+	db       *data.Queries
+	provider location.Provider
+	ttl      time.Duration
+	cache    *cache.TTL[string, data.Geolocation]
+	now      func() time.Time
+}
+
+// NewGeolocationRepository reuses each IP's geolocation for ttl, in memory
+// and in SQLite, before looking it up again since addresses get reassigned.
+func NewGeolocationRepository(db *data.Queries, provider location.Provider, ttl time.Duration) GeolocationRepository {
+	return &geolocationRepository{
+		db:       db,
+		provider: provider,
+		ttl:      ttl,
+		cache:    cache.NewTTL[string, data.Geolocation](ttl),
+		now:      time.Now,
+	}
+}
+
+func (r *geolocationRepository) ForIP(ctx context.Context, ip string) (data.Geolocation, error) {
+	if entry, ok := r.cache.Get(ip); ok {
+		return entry, nil
+	}
+
+	stored, err := r.db.GetGeolocation(ctx, ip)
+	switch {
+	case err == nil && r.now().Sub(stored.TimeFetched) < r.ttl:
+		r.cache.Set(ip, stored)
+		return stored, nil
+	case err == nil:
+		log.Printf("refetching stale location for %v", ip)
+	case errors.Is(err, sql.ErrNoRows):
+		log.Printf("fetching location for %v", ip)
+	default:
+		return stored, fmt.Errorf("error reading geolocation for %v: %w", ip, err)
+	}
+
+	loc, err := r.provider.ForIP(ctx, ip)
+	if err != nil {
+		// A stale location is better than none while the upstream is down,
+		// but not once it says the address can't be located anymore.
+		if stored.Ip != "" && !errors.Is(err, location.ErrNotFound) && ctx.Err() == nil {
+			log.Printf("error refetching location for %v, using the stale one: %v", ip, err)
+			return stored, nil
+		}
+		return data.Geolocation{}, err
+	}
+
+	entry, err := r.db.UpsertGeolocation(ctx, data.UpsertGeolocationParams{
+		Ip:          ip,
+		Latitude:    loc.Lat,
+		Longitude:   loc.Lon,
+		City:        loc.City,
+		Country:     loc.Country,
+		Timezone:    loc.Timezone,
+		TimeFetched: r.now().UTC(),
+	})
+	if err != nil {
+		return entry, fmt.Errorf("error storing geolocation for %v: %w", ip, err)
+	}
+
+	r.cache.Set(ip, entry)
+	return entry, nil
+}
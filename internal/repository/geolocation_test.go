@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"weather/internal/data"
+	"weather/internal/location"
+
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// movingLocator reports the IP in whichever city it is set to, or fails
+// with err.
+type movingLocator struct {
+	city  string
+	err   error
+	calls int
+}
+
+func (p *movingLocator) ForIP(ctx context.Context, ip string) (location.Geolocation, error) {
+	p.calls++
+	if p.err != nil {
+		return location.Geolocation{}, p.err
+	}
+	return location.Geolocation{City: p.city, Timezone: "UTC"}, nil
+}
+
+func TestGeolocationRepository(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := openTestDB(t)
+	db := data.New(sqlDB)
+	provider := &movingLocator{city: "Berlin"}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// newRepository starts with an empty cache, so lookups reach SQLite.
+	newRepository := func() GeolocationRepository {
+		geolocations := NewGeolocationRepository(db, provider, 24*time.Hour).(*geolocationRepository)
+		geolocations.now = func() time.Time { return now }
+		return geolocations
+	}
+
+	lookup := func(t *testing.T) data.Geolocation {
+		t.Helper()
+
+		loc, err := newRepository().ForIP(ctx, "192.0.2.1")
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		return loc
+	}
+
+	t.Run("reuses stored locations within the TTL", func(t *testing.T) {
+		lookup(t)
+		now = now.Add(23 * time.Hour)
+
+		if loc := lookup(t); loc.City != "Berlin" || provider.calls != 1 {
+			t.Errorf("expected the stored location without a lookup, got %q after %d calls", loc.City, provider.calls)
+		}
+	})
+
+	t.Run("looks up stale locations again", func(t *testing.T) {
+		provider.city = "Paris"
+		now = now.Add(2 * time.Hour)
+
+		if loc := lookup(t); loc.City != "Paris" || !loc.TimeFetched.Equal(now) {
+			t.Errorf("expected the new location fetched now, got %+v", loc)
+		}
+
+		var rows int
+		if err := sqlDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM geolocations").Scan(&rows); err != nil || rows != 1 {
+			t.Errorf("expected the row to be replaced, got %d rows (%v)", rows, err)
+		}
+	})
+
+	t.Run("falls back to stale locations while the upstream is down", func(t *testing.T) {
+		provider.err = errors.New("upstream is down")
+		now = now.Add(48 * time.Hour)
+
+		if loc := lookup(t); loc.City != "Paris" {
+			t.Errorf("expected the stale location, got %+v", loc)
+		}
+	})
+
+	t.Run("doesn't fall back for addresses that can't be located anymore", func(t *testing.T) {
+		provider.err = location.ErrNotFound
+
+		if _, err := newRepository().ForIP(ctx, "192.0.2.1"); !errors.Is(err, location.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
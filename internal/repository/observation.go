@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"weather/internal/cache"
+	"weather/internal/data"
+	"weather/internal/observation"
+	"weather/internal/weather"
+
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// latLon is a grid cell, in hundredths of a degree. Open-Meteo only resolves
+// coordinates to two decimal places, so visitors in the same cell share an
+// observation.
+type latLon struct {
+	lat int64
+	lon int64
+}
+
+func keyFor(lat float64, lon float64) latLon {
+	return latLon{lat: int64(math.Round(lat * 100)), lon: int64(math.Round(lon * 100))}
+}
+
+// center is the middle of the cell, in degrees.
+func (k latLon) center() (float64, float64) {
+	return float64(k.lat) / 100, float64(k.lon) / 100
+}
+
+func (k latLon) String() string {
+	return fmt.Sprintf("%d,%d", k.lat, k.lon)
+}
+
+type observationRepository struct {
+	db       *data.Queries
+	provider weather.Provider
+	ttl      time.Duration
+	cache    *cache.TTL[latLon, data.Observation]
+	byID     *cache.TTL[int64, data.Observation]
+	inflight singleflight.Group
+	now      func() time.Time
+}
+
+func NewObservationRepository(db *data.Queries, provider weather.Provider, ttl time.Duration) ObservationRepository {
+	return &observationRepository{
+		db:       db,
+		provider: provider,
+		ttl:      ttl,
+		cache:    cache.NewTTL[latLon, data.Observation](ttl),
+		byID:     cache.NewTTL[int64, data.Observation](ttl),
+		now:      time.Now,
+	}
+}
+
+// ForLocation returns the observation for loc's grid cell in the current
+// time bucket, fetching it upstream when there isn't one yet. Concurrent
+// requests for the same cell share a single upstream call.
+func (r *observationRepository) ForLocation(ctx context.Context, loc data.Geolocation) (data.Observation, error) {
+	key := keyFor(loc.Latitude, loc.Longitude)
+	bucket := r.now().UTC().Truncate(r.ttl)
+	if obs, ok := r.cache.Get(key); ok && obs.BucketUtc.Equal(bucket) {
+		return obs, nil
+	}
+
+	// The first request to arrive does the work on behalf of everyone
+	// waiting, so a visitor giving up mustn't cancel it for the others.
+	results := r.inflight.DoChan(key.String()+"@"+bucket.Format(time.RFC3339), func() (any, error) {
+		return r.resolve(context.WithoutCancel(ctx), loc, key, bucket)
+	})
+
+	select {
+	case <-ctx.Done():
+		return data.Observation{}, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return data.Observation{}, result.Err
+		}
+		return result.Val.(data.Observation), nil
+	}
+}
+
+// ForObservedLocation is ForLocation for cells that have been observed
+// before, failing with ErrNotFound elsewhere. A cache hit means the cell has
+// been observed, so only misses check SQLite.
+func (r *observationRepository) ForObservedLocation(ctx context.Context, loc data.Geolocation) (data.Observation, error) {
+	key := keyFor(loc.Latitude, loc.Longitude)
+	if _, ok := r.cache.Get(key); !ok {
+		observed, err := r.db.HasObservationInCell(ctx, data.HasObservationInCellParams{CellLat: key.lat, CellLon: key.lon})
+		if err != nil {
+			return data.Observation{}, fmt.Errorf("error checking for observations in %v: %w", key, err)
+		}
+		if observed == 0 {
+			return data.Observation{}, ErrNotFound
+		}
+	}
+
+	return r.ForLocation(ctx, loc)
+}
+
+// resolve reads the cell's observation for bucket from SQLite, or else
+// fetches and stores it. The cell's time zone and country are taken from
+// loc, since a cell is far too small to straddle either in practice.
+func (r *observationRepository) resolve(ctx context.Context, loc data.Geolocation, key latLon, bucket time.Time) (data.Observation, error) {
+	obs, err := r.db.GetObservationInCell(ctx, data.GetObservationInCellParams{
+		CellLat:   key.lat,
+		CellLon:   key.lon,
+		BucketUtc: bucket,
+	})
+	switch {
+	case err == nil:
+		r.remember(key, obs)
+		return obs, nil
+	case errors.Is(err, sql.ErrNoRows):
+		break
+	default:
+		return obs, fmt.Errorf("error reading recent observation: %w", err)
+	}
+
+	// The observation is shared by the whole cell, so it is made at the
+	// cell's center rather than wherever the first visitor happens to be.
+	lat, lon := key.center()
+	wth, err := r.provider.Current(ctx, lat, lon)
+	if err != nil {
+		return obs, err
+	}
+
+	tzloc, err := time.LoadLocation(loc.Timezone)
+	if err != nil {
+		tzloc = time.UTC
+	}
+
+	// Providers report when their conditions were observed, which can be a
+	// few minutes before now.
+	observed := wth.Time
+	if observed.IsZero() {
+		observed = r.now()
+	}
+
+	category, _ := wth.WeatherCode.Category()
+
+	obs, err = r.db.UpsertObservation(ctx, data.UpsertObservationParams{
+		Latitude:         lat,
+		Longitude:        lon,
+		Timezone:         loc.Timezone,
+		TempC:            wth.TemperatureC,
+		TempF:            weather.CToF(wth.TemperatureC),
+		Rain:             wth.RainMM,
+		Snowfall:         wth.SnowfallCM,
+		WeatherCode:      wth.WeatherCode,
+		RelativeHumidity: wth.RelativeHumidity,
+		TimeUtc:          observed.UTC(),
+		TimeLocal:        observed.In(tzloc),
+		Country:          loc.Country,
+
+		ApparentTempC:      wth.ApparentTemperatureC,
+		Precipitation:      wth.PrecipitationMM,
+		WindSpeedKmh:       wth.WindSpeedKMH,
+		WindDirection:      wth.WindDirection,
+		WindGustsKmh:       wth.WindGustsKMH,
+		SurfacePressureHpa: wth.SurfacePressureHPA,
+		CloudCover:         wth.CloudCover,
+		UvIndex:            wth.UVIndex,
+		IsDay:              wth.IsDay,
+
+		CellLat:   key.lat,
+		CellLon:   key.lon,
+		BucketUtc: bucket,
+
+		WeatherCategory: category.Name,
+	})
+	if err != nil {
+		return obs, fmt.Errorf("error storing observation: %w", err)
+	}
+
+	r.remember(key, obs)
+	return obs, nil
+}
+
+func (r *observationRepository) ByID(ctx context.Context, id int64) (data.Observation, error) {
+	if obs, ok := r.byID.Get(id); ok {
+		return obs, nil
+	}
+
+	obs, err := r.db.GetObservation(ctx, id)
+	switch {
+	case err == nil:
+		r.byID.Set(id, obs)
+		return obs, nil
+	case errors.Is(err, sql.ErrNoRows):
+		return obs, ErrNotFound
+	default:
+		return obs, fmt.Errorf("error reading observation %v: %w", id, err)
+	}
+}
+
+func (r *observationRepository) Prior(ctx context.Context, obs data.Observation) (*observation.PriorObservation, error) {
+	return observation.ResolvePriorObservation(ctx, obs, r.db)
+}
+
+func (r *observationRepository) List(ctx context.Context, params data.ListObservationsParams) ([]data.ListObservationsRow, error) {
+	rows, err := r.db.ListObservations(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("error listing observations: %w", err)
+	}
+
+	return rows, nil
+}
+
+func (r *observationRepository) Countries(ctx context.Context) ([]string, error) {
+	countries, err := r.db.ListObservationCountries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing observation countries: %w", err)
+	}
+
+	return countries, nil
+}
+
+func (r *observationRepository) remember(key latLon, obs data.Observation) {
+	r.cache.Set(key, obs)
+	r.byID.Set(obs.ID, obs)
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"weather/internal/data"
+	"weather/internal/database"
+	"weather/internal/migrate"
+	"weather/internal/weather"
+
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", database.DSN(":memory:", false))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := migrate.Load(os.DirFS("../../sqlite/migrations"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := migrate.Up(context.Background(), db, migrations); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return db
+}
+
+// observedAt is when countingProvider says its conditions were observed.
+var observedAt = time.Date(2024, 5, 1, 11, 45, 0, 0, time.UTC)
+
+// countingProvider reports the same conditions every time, counting calls,
+// announcing each on started and holding it until release is closed.
+type countingProvider struct {
+	calls   atomic.Int64
+	started chan [2]float64
+	release chan struct{}
+}
+
+func (p *countingProvider) Current(ctx context.Context, lat float64, lon float64) (weather.Conditions, error) {
+	p.calls.Add(1)
+	select {
+	case p.started <- [2]float64{lat, lon}:
+	default:
+	}
+	<-p.release
+	return weather.Conditions{TemperatureC: 11.5, WeatherCode: 3, Time: observedAt}, nil
+}
+
+func TestObservationRepository(t *testing.T) {
+	ctx := context.Background()
+	db := data.New(openTestDB(t))
+	provider := &countingProvider{started: make(chan [2]float64, 1), release: make(chan struct{})}
+
+	// Every request falls in the same bucket, however long the test takes.
+	now := time.Date(2024, 5, 1, 12, 10, 0, 0, time.UTC)
+	newRepository := func() ObservationRepository {
+		observations := NewObservationRepository(db, provider, time.Hour).(*observationRepository)
+		observations.now = func() time.Time { return now }
+		return observations
+	}
+
+	t.Run("coalesces requests for the same cell", func(t *testing.T) {
+		observations := newRepository()
+
+		ids := make([]int64, 8)
+		var wg sync.WaitGroup
+		for i := range ids {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Both round to the same hundredth of a degree.
+				loc := data.Geolocation{Latitude: 52.521 - float64(i%2)*0.002, Longitude: 13.42, Timezone: "UTC"}
+				obs, err := observations.ForLocation(ctx, loc)
+				if err != nil {
+					t.Errorf("%v", err)
+				}
+				ids[i] = obs.ID
+			}()
+		}
+
+		// Requests that arrive after the upstream call finishes find the
+		// stored observation instead, so either way there is one call.
+		center := <-provider.started
+		close(provider.release)
+		wg.Wait()
+
+		if center != [2]float64{52.52, 13.42} {
+			t.Errorf("expected the cell's center to be observed, got %v", center)
+		}
+
+		if calls := provider.calls.Load(); calls != 1 {
+			t.Errorf("expected 1 upstream call, got %d", calls)
+		}
+		for _, id := range ids {
+			if id != ids[0] {
+				t.Errorf("expected every request to share observation %d, got %v", ids[0], ids)
+				break
+			}
+		}
+	})
+
+	t.Run("reuses the stored observation", func(t *testing.T) {
+		observations := newRepository()
+
+		obs, err := observations.ForLocation(ctx, data.Geolocation{Latitude: 52.52, Longitude: 13.42})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if calls := provider.calls.Load(); calls != 1 {
+			t.Errorf("expected no more upstream calls, got %d", calls)
+		}
+		if obs.Latitude != 52.52 || obs.Longitude != 13.42 {
+			t.Errorf("expected the cell's center to be stored, got %v, %v", obs.Latitude, obs.Longitude)
+		}
+		if !obs.BucketUtc.Equal(now.Truncate(time.Hour)) {
+			t.Errorf("expected bucket %v, got %v", now.Truncate(time.Hour), obs.BucketUtc)
+		}
+		if obs.WeatherCategory != "cloudy" {
+			t.Errorf("expected code 3 to be stored as cloudy, got %q", obs.WeatherCategory)
+		}
+		if !obs.TimeUtc.Equal(observedAt) {
+			t.Errorf("expected the provider's observation time %v, got %v", observedAt, obs.TimeUtc)
+		}
+	})
+
+	t.Run("upserts into an existing bucket", func(t *testing.T) {
+		params := data.UpsertObservationParams{
+			Latitude:  1,
+			Longitude: 2,
+			Timezone:  "UTC",
+			TimeUtc:   time.Now().UTC(),
+			TimeLocal: time.Now().UTC(),
+			CellLat:   100,
+			CellLon:   200,
+			BucketUtc: time.Now().UTC().Truncate(time.Hour),
+		}
+
+		first, err := db.UpsertObservation(ctx, params)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		params.TempC = 30
+		second, err := db.UpsertObservation(ctx, params)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if first.ID != second.ID || second.TempC != first.TempC {
+			t.Errorf("expected the first observation back, got %+v", second)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"weather/internal/data"
+	"weather/internal/observation"
+	"weather/internal/weather"
+
+	"context"
+	"database/sql"
+)
+
+// ErrNotFound is data.ErrNotFound, so either can be checked for.
+var ErrNotFound = data.ErrNotFound
+
+// TxDB is a database that can run queries and begin transactions, like
+// *sql.DB or *database.DB.
+type TxDB interface {
+	data.DBTX
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// GeolocationRepository resolves the location of a visitor's IP, checking the
+// in-process cache first, then SQLite, then the upstream geolocation API.
+type GeolocationRepository interface {
+	ForIP(ctx context.Context, ip string) (data.Geolocation, error)
+}
+
+// ObservationRepository resolves the current weather at a location, checking
+// the in-process cache first, then SQLite, then the upstream weather API.
+type ObservationRepository interface {
+	ForLocation(ctx context.Context, loc data.Geolocation) (data.Observation, error)
+	// ForObservedLocation is ForLocation, but fails with ErrNotFound instead
+	// of fetching weather for a cell that has never been observed.
+	ForObservedLocation(ctx context.Context, loc data.Geolocation) (data.Observation, error)
+	ByID(ctx context.Context, id int64) (data.Observation, error)
+	// Prior picks the drawn observation from another visitor that best
+	// matches obs, or nil when nothing has been drawn yet.
+	Prior(ctx context.Context, obs data.Observation) (*observation.PriorObservation, error)
+	// List reads a page of observations, newest first.
+	List(ctx context.Context, params data.ListObservationsParams) ([]data.ListObservationsRow, error)
+	// Countries lists every country observations have been made in.
+	Countries(ctx context.Context) ([]string, error)
+}
+
+// DrawingRepository stores what visitors drew for their observations, and
+// renders drawings as PNGs, keeping each rendered copy in SQLite.
+type DrawingRepository interface {
+	// ForObservation returns the drawing for an observation, failing with
+	// ErrNotFound when it has none.
+	ForObservation(ctx context.Context, observationID int64) (data.ObservationDrawing, error)
+	// Add stores a drawing, failing with ErrNotFound when its observation
+	// doesn't exist, data.ErrConflict when the observation already has a
+	// drawing and data.ErrConstraint when it can't be stored otherwise.
+	Add(ctx context.Context, drawing data.ObservationDrawing) error
+	// PNG renders the drawing for an observation, failing with ErrNotFound
+	// when it has none.
+	PNG(ctx context.Context, observationID int64) ([]byte, error)
+	// List reads a page of drawn observations, newest first.
+	List(ctx context.Context, params data.ListDrawnObservationsParams) ([]data.ListDrawnObservationsRow, error)
+	// CountByCategory counts drawn observations by weather category.
+	CountByCategory(ctx context.Context) ([]data.CountDrawnObservationsByCategoryRow, error)
+}
+
+// ForecastRepository resolves the forecast at a location, checking the
+// in-process cache first, then SQLite, then the upstream weather API.
+type ForecastRepository interface {
+	ForLocation(ctx context.Context, loc data.Geolocation) (weather.Forecast, error)
+}
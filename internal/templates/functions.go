@@ -1,7 +1,33 @@
 package templates
 
-import "time"
+import (
+	"weather/internal/weather"
+
+	"time"
+)
 
 func AsDateInputValue(t time.Time) string {
 	return t.Format(time.DateTime)
 }
+
+func WeatherDescription(code weather.Code) string {
+	return code.Description()
+}
+
+// WeatherCategory names the category of code, empty for unknown codes.
+func WeatherCategory(code weather.Code) string {
+	category, _ := code.Category()
+	return category.Name
+}
+
+func WeatherSeverity(code weather.Code) string {
+	return code.Severity().String()
+}
+
+func WeatherIcon(code weather.Code, day bool) string {
+	return code.Icon(day)
+}
+
+func IsDaytime(local time.Time) bool {
+	return weather.IsDaytime(local)
+}
@@ -19,7 +19,13 @@ func Init(fs embed.FS, constants interface{}, rootTemplatePath string, commonTem
 	}
 
 	templateFunctions := template.FuncMap{
-		"asdateinputvalue": AsDateInputValue,
+		"asdateinputvalue":   AsDateInputValue,
+		"const":              func() interface{} { return constants },
+		"weatherdescription": WeatherDescription,
+		"weathercategory":    WeatherCategory,
+		"weatherseverity":    WeatherSeverity,
+		"weathericon":        WeatherIcon,
+		"isdaytime":          IsDaytime,
 	}
 
 	return &TemplateEngine{constants: constants, root: template.Must(
@@ -35,8 +41,12 @@ type TemplateEnvironment struct {
 }
 
 func (te *TemplateEngine) Render(w http.ResponseWriter, path string, data any) error {
-	tmpl := template.Must(te.root.Clone())
-	tmpl, err := tmpl.ParseFiles(path)
+	tmpl, err := te.root.Clone()
+	if err != nil {
+		return fmt.Errorf("error cloning root template: %w", err)
+	}
+
+	tmpl, err = tmpl.ParseFiles(path)
 	if err != nil {
 		return err
 	}
@@ -46,3 +56,12 @@ func (te *TemplateEngine) Render(w http.ResponseWriter, path string, data any) e
 		Data:  data,
 	})
 }
+
+func (te *TemplateEngine) RenderFragment(w http.ResponseWriter, name string, data any) error {
+	tmpl, err := te.root.Clone()
+	if err != nil {
+		return fmt.Errorf("error cloning root template: %w", err)
+	}
+
+	return tmpl.ExecuteTemplate(w, name, data)
+}
@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ProblemsFragment is the template fragment WriteError renders for htmx
+// requests. It is executed with the ValidationProblems.
+const ProblemsFragment = "validation-problems"
+
+// Renderer renders named template fragments, like
+// *templates.TemplateEngine.
+type Renderer interface {
+	RenderFragment(w http.ResponseWriter, name string, data any) error
+}
+
+// WriteError responds with the problems err carries, or with a single
+// problem under field when it carries none. htmx requests get
+// ProblemsFragment swapped into the element matching target, clients that
+// ask for JSON get WriteJSON's envelope, and everyone else gets plain text.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, field string, render Renderer, target string) {
+	WriteErrorStatus(w, r, http.StatusUnprocessableEntity, err, field, render, target)
+}
+
+// WriteErrorStatus is WriteError with a status other than 422, for problems
+// like a conflict with what's already stored. htmx requests still get
+// ProblemsFragment, which the client swaps for any 4xx carrying HX-Retarget.
+func WriteErrorStatus(w http.ResponseWriter, r *http.Request, status int, err error, field string, render Renderer, target string) {
+	problems := ProblemsOf(err, field)
+
+	switch {
+	case r.Header.Get("HX-Request") == "true" && render != nil:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("HX-Retarget", target)
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(status)
+		if err := render.RenderFragment(w, ProblemsFragment, problems); err != nil {
+			log.Printf("error rendering validation problems: %v", err)
+		}
+	case PrefersJSON(r):
+		writeJSON(w, status, problems)
+	default:
+		http.Error(w, (&ValidationError{Problems: problems}).Error(), status)
+	}
+}
+
+// WriteJSON responds with the problems err carries, or with a single problem
+// under field, in the same error envelope as the JSON API:
+//
+//	{"error": {"code": "invalid", "message": "...", "problems": {...}}}
+func WriteJSON(w http.ResponseWriter, err error, field string) {
+	writeJSON(w, http.StatusUnprocessableEntity, ProblemsOf(err, field))
+}
+
+// writeJSON writes the error envelope with status, coding it the same way
+// as the JSON API does.
+func writeJSON(w http.ResponseWriter, status int, problems ValidationProblems) {
+	code := "invalid"
+	switch status {
+	case http.StatusNotFound:
+		code = "not_found"
+	case http.StatusConflict:
+		code = "conflict"
+	}
+
+	type envelope struct {
+		Code     string             `json:"code"`
+		Message  string             `json:"message"`
+		Problems ValidationProblems `json:"problems"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error envelope `json:"error"`
+	}{
+		Error: envelope{
+			Code:     code,
+			Message:  (&ValidationError{Problems: problems}).Error(),
+			Problems: problems,
+		},
+	})
+}
+
+// PrefersJSON reports whether r accepts JSON before HTML.
+func PrefersJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "application/json":
+			return true
+		case "text/html", "*/*":
+			return false
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package validation
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // so Timezone doesn't depend on the host's zoneinfo
+)
+
+// Rule checks a value, returning a description of what's wrong with it or
+// "" if nothing is.
+type Rule[T any] func(value T) string
+
+// Field checks value against rules in order, recording the first problem
+// under name. It reports whether value passed.
+func Field[T any](v *Validator, name string, value T, rules ...Rule[T]) bool {
+	for _, rule := range rules {
+		if problem := rule(value); problem != "" {
+			v.Problem(name, "%s", problem)
+			return false
+		}
+	}
+
+	return true
+}
+
+// Required rejects blank strings.
+func Required(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "is required"
+	}
+	return ""
+}
+
+// Between rejects values outside min and max, inclusive.
+func Between[T cmp.Ordered](min T, max T) Rule[T] {
+	return func(value T) string {
+		// Comparisons with NaN are always false, so check the negation.
+		if !(value >= min && value <= max) {
+			return fmt.Sprintf("must be between %v and %v", min, max)
+		}
+		return ""
+	}
+}
+
+// AtLeast rejects values below min.
+func AtLeast[T cmp.Ordered](min T) Rule[T] {
+	return func(value T) string {
+		if !(value >= min) {
+			return fmt.Sprintf("must be at least %v", min)
+		}
+		return ""
+	}
+}
+
+// Matches rejects strings that don't match pattern, which description says
+// in words.
+func Matches(pattern *regexp.Regexp, description string) Rule[string] {
+	return func(value string) string {
+		if !pattern.MatchString(value) {
+			return "must be " + description
+		}
+		return ""
+	}
+}
+
+// OneOf rejects values that aren't in allowed.
+func OneOf[T comparable](allowed ...T) Rule[T] {
+	return func(value T) string {
+		if !slices.Contains(allowed, value) {
+			return fmt.Sprintf("must be one of %v", allowed)
+		}
+		return ""
+	}
+}
+
+// Timezone rejects names that aren't in the IANA timezone database.
+// time.LoadLocation also accepts "" and "Local", which aren't.
+func Timezone(value string) string {
+	if value == "" || value == "Local" {
+		return fmt.Sprintf("%q isn't an IANA timezone", value)
+	}
+
+	if _, err := time.LoadLocation(value); err != nil {
+		return fmt.Sprintf("%q isn't an IANA timezone", value)
+	}
+	return ""
+}
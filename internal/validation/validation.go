@@ -2,6 +2,9 @@ package validation
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 )
 
 var ErrValidation = errors.New("Could not validate.")
@@ -11,3 +14,94 @@ type Validates interface {
 }
 
 type ValidationProblems = map[string]string
+
+// ValidationError carries a problem for each field that failed validation.
+// It wraps ErrValidation.
+type ValidationError struct {
+	Problems ValidationProblems
+}
+
+// Problem returns a ValidationError with a single problem.
+func Problem(field string, format string, args ...any) error {
+	return &ValidationError{Problems: ValidationProblems{
+		field: fmt.Sprintf(format, args...),
+	}}
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Problems))
+	for field := range e.Problems {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	problems := make([]string, len(fields))
+	for i, field := range fields {
+		problems[i] = field + ": " + e.Problems[field]
+	}
+
+	return strings.Join(problems, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// ProblemsOf returns the problems carried by err, or a single problem under
+// field describing err when it doesn't carry any.
+func ProblemsOf(err error, field string) ValidationProblems {
+	invalid := &ValidationError{}
+	if errors.As(err, &invalid) {
+		return invalid.Problems
+	}
+
+	return ValidationProblems{field: err.Error()}
+}
+
+// Validator collects problems across the fields of a form or response.
+type Validator struct {
+	problems ValidationProblems
+}
+
+// Problem records a problem with field, keeping the first one recorded.
+func (v *Validator) Problem(field string, format string, args ...any) {
+	if v.problems == nil {
+		v.problems = ValidationProblems{}
+	}
+	if _, ok := v.problems[field]; !ok {
+		v.problems[field] = fmt.Sprintf(format, args...)
+	}
+}
+
+// Merge records the problems err carries, or a single problem under field
+// describing err when it doesn't carry any.
+func (v *Validator) Merge(err error, field string) {
+	for f, problem := range ProblemsOf(err, field) {
+		v.Problem(f, "%s", problem)
+	}
+}
+
+// Has reports whether field has a problem.
+func (v *Validator) Has(field string) bool {
+	_, ok := v.problems[field]
+	return ok
+}
+
+// Problems returns the problems recorded so far, or nil.
+func (v *Validator) Problems() ValidationProblems {
+	return v.problems
+}
+
+// Err returns a ValidationError if any problems were recorded.
+func (v *Validator) Err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: v.problems}
+}
+
+// Validate returns the problems and error, in the form Validates expects.
+func (v *Validator) Validate() (ValidationProblems, error) {
+	return v.problems, v.Err()
+}
@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type fragmentRenderer struct{}
+
+func (fragmentRenderer) RenderFragment(w http.ResponseWriter, name string, data any) error {
+	_, err := fmt.Fprintf(w, "%s %v", name, data)
+	return err
+}
+
+func TestValidator(t *testing.T) {
+	v := Validator{}
+	Field(&v, "name", " ", Required)
+	Field(&v, "lat", 91.5, Between(-90.0, 90.0))
+	Field(&v, "code", "abc", Matches(regexp.MustCompile(`^[0-9]+$`), "digits"))
+	Field(&v, "unit", "°F", OneOf("°C"))
+	Field(&v, "lon", 10.0, Between(-180.0, 180.0))
+	v.Problem("name", "is ignored after the first problem")
+
+	err := v.Err()
+
+	t.Run("collects a problem per field", func(t *testing.T) {
+		expected := ValidationProblems{
+			"name": "is required",
+			"lat":  "must be between -90 and 90",
+			"code": "must be digits",
+			"unit": "must be one of [°C]",
+		}
+		if fmt.Sprint(v.Problems()) != fmt.Sprint(expected) {
+			t.Errorf("expected %v, got %v", expected, v.Problems())
+		}
+	})
+
+	t.Run("wraps ErrValidation", func(t *testing.T) {
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("expected ErrValidation, got %v", err)
+		}
+		if ProblemsOf(fmt.Errorf("wrapped: %w", err), "other")["lat"] == "" {
+			t.Errorf("expected problems to survive wrapping")
+		}
+	})
+
+	t.Run("writes problems for the client", func(t *testing.T) {
+		for _, tc := range []struct {
+			name        string
+			headers     map[string]string
+			contentType string
+			contains    string
+		}{
+			{"htmx", map[string]string{"HX-Request": "true"}, "text/html; charset=utf-8", ProblemsFragment},
+			{"json", map[string]string{"Accept": "application/json"}, "application/json", `"lat":"must be between -90 and 90"`},
+			{"text", nil, "text/plain; charset=utf-8", "lat: must be between -90 and 90"},
+		} {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			for key, value := range tc.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			WriteError(w, r, err, "form", fragmentRenderer{}, "find .validation-problems")
+
+			if w.Code != http.StatusUnprocessableEntity || w.Header().Get("Content-Type") != tc.contentType {
+				t.Errorf("%s: unexpected response %d %s", tc.name, w.Code, w.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(w.Body.String(), tc.contains) {
+				t.Errorf("%s: expected %q in %q", tc.name, tc.contains, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("describes plain errors under the given field", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+
+		WriteError(w, r, errors.New("bad"), "drawing", nil, "")
+
+		body := struct {
+			Error struct{ Problems ValidationProblems }
+		}{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error.Problems["drawing"] != "bad" {
+			t.Errorf("unexpected body %q (%v)", w.Body.String(), err)
+		}
+	})
+
+	t.Run("writes other statuses the same way", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			headers  map[string]string
+			contains string
+		}{
+			{"htmx", map[string]string{"HX-Request": "true"}, ProblemsFragment},
+			{"json", map[string]string{"Accept": "application/json"}, `"code":"conflict"`},
+			{"text", nil, "drawing: already drawn"},
+		} {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			for key, value := range tc.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			WriteErrorStatus(w, r, http.StatusConflict, errors.New("already drawn"), "drawing", fragmentRenderer{}, "#problems")
+
+			if w.Code != http.StatusConflict {
+				t.Errorf("%s: expected 409, got %d", tc.name, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tc.contains) {
+				t.Errorf("%s: expected %q in %q", tc.name, tc.contains, w.Body.String())
+			}
+		}
+	})
+}
+
+func TestTimezone(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"Europe/Berlin": true,
+		"Etc/GMT-5":     true,
+		"UTC":           true,
+		"":              false,
+		"Local":         false,
+		"Mars/Olympus":  false,
+	} {
+		if problem := Timezone(name); (problem == "") != valid {
+			t.Errorf("expected Timezone(%q) valid to be %v, got %q", name, valid, problem)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package weather
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Code is a WMO weather interpretation code (WW), as reported by Open-Meteo.
+//
+// https://open-meteo.com/en/docs
+type Code int
+
+// Severity ranks how disruptive the weather a code describes is.
+type Severity int
+
+const (
+	SeverityNone Severity = iota
+	SeverityLight
+	SeverityModerate
+	SeverityHeavy
+	SeveritySevere
+)
+
+var severityNames = [...]string{"none", "light", "moderate", "heavy", "severe"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return "unknown"
+	}
+
+	return severityNames[s]
+}
+
+// Category is a contiguous range of related codes. Observations store the
+// name of theirs, so they can be filtered and counted by it.
+type Category struct {
+	Name  string
+	Label string
+	Min   Code
+	Max   Code
+}
+
+// Categories are every category, in code order.
+var Categories = []Category{
+	{Name: "clear", Label: "Clear", Min: 0, Max: 1},
+	{Name: "cloudy", Label: "Cloudy", Min: 2, Max: 3},
+	{Name: "fog", Label: "Fog", Min: 45, Max: 48},
+	{Name: "drizzle", Label: "Drizzle", Min: 51, Max: 57},
+	{Name: "rain", Label: "Rain", Min: 61, Max: 67},
+	{Name: "snow", Label: "Snow", Min: 71, Max: 77},
+	{Name: "showers", Label: "Showers", Min: 80, Max: 86},
+	{Name: "thunderstorm", Label: "Thunderstorm", Min: 95, Max: 99},
+}
+
+// LookupCategory returns the category called name.
+func LookupCategory(name string) (Category, bool) {
+	for _, category := range Categories {
+		if category.Name == name {
+			return category, true
+		}
+	}
+
+	return Category{}, false
+}
+
+type codeInfo struct {
+	description string
+	severity    Severity
+	icon        string
+	// dayNight icons have -day and -night variants.
+	dayNight bool
+}
+
+// codes describes every code Open-Meteo reports.
+//
+// Code 	Description
+// 0 	Clear sky
+// 1, 2, 3 	Mainly clear, partly cloudy, and overcast
+// 45, 48 	Fog and depositing rime fog
+// 51, 53, 55 	Drizzle: Light, moderate, and dense intensity
+// 56, 57 	Freezing Drizzle: Light and dense intensity
+// 61, 63, 65 	Rain: Slight, moderate and heavy intensity
+// 66, 67 	Freezing Rain: Light and heavy intensity
+// 71, 73, 75 	Snow fall: Slight, moderate, and heavy intensity
+// 77 	Snow grains
+// 80, 81, 82 	Rain showers: Slight, moderate, and violent
+// 85, 86 	Snow showers slight and heavy
+// 95 * 	Thunderstorm: Slight or moderate
+// 96, 99 * 	Thunderstorm with slight and heavy hail
+var codes = map[Code]codeInfo{
+	0: {"Clear sky", SeverityNone, "clear", true},
+	1: {"Mainly clear", SeverityNone, "mostly-clear", true},
+	2: {"Partly cloudy", SeverityNone, "partly-cloudy", true},
+	3: {"Overcast", SeverityNone, "overcast", false},
+
+	45: {"Fog", SeverityLight, "fog", false},
+	48: {"Depositing rime fog", SeverityModerate, "fog", false},
+
+	51: {"Light drizzle", SeverityLight, "drizzle", false},
+	53: {"Moderate drizzle", SeverityModerate, "drizzle", false},
+	55: {"Dense drizzle", SeverityHeavy, "drizzle", false},
+	56: {"Light freezing drizzle", SeverityModerate, "freezing-drizzle", false},
+	57: {"Dense freezing drizzle", SeverityHeavy, "freezing-drizzle", false},
+
+	61: {"Slight rain", SeverityLight, "rain", false},
+	63: {"Moderate rain", SeverityModerate, "rain", false},
+	65: {"Heavy rain", SeverityHeavy, "rain", false},
+	66: {"Light freezing rain", SeverityModerate, "freezing-rain", false},
+	67: {"Heavy freezing rain", SeveritySevere, "freezing-rain", false},
+
+	71: {"Slight snowfall", SeverityLight, "snow", false},
+	73: {"Moderate snowfall", SeverityModerate, "snow", false},
+	75: {"Heavy snowfall", SeverityHeavy, "snow", false},
+	77: {"Snow grains", SeverityLight, "snow-grains", false},
+
+	80: {"Slight rain showers", SeverityLight, "rain-showers", true},
+	81: {"Moderate rain showers", SeverityModerate, "rain-showers", true},
+	82: {"Violent rain showers", SeveritySevere, "rain-showers", true},
+	85: {"Slight snow showers", SeverityLight, "snow-showers", true},
+	86: {"Heavy snow showers", SeverityHeavy, "snow-showers", true},
+
+	95: {"Thunderstorm", SeverityHeavy, "thunderstorm", false},
+	96: {"Thunderstorm with slight hail", SeveritySevere, "thunderstorm-hail", false},
+	99: {"Thunderstorm with heavy hail", SeveritySevere, "thunderstorm-hail", false},
+}
+
+// ParseCode parses a code written as a decimal integer, like the TEXT
+// observations store.
+func ParseCode(s string) (Code, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || !Code(n).Valid() {
+		return 0, fmt.Errorf("%q isn't a WMO weather code", s)
+	}
+
+	return Code(n), nil
+}
+
+// Valid reports whether c is a code Open-Meteo reports.
+func (c Code) Valid() bool {
+	_, ok := codes[c]
+	return ok
+}
+
+func (c Code) String() string {
+	return strconv.Itoa(int(c))
+}
+
+// Description describes c in words, like "Moderate rain".
+func (c Code) Description() string {
+	if info, ok := codes[c]; ok {
+		return info.description
+	}
+
+	return "Unknown weather"
+}
+
+// Category returns the category c belongs to.
+func (c Code) Category() (Category, bool) {
+	for _, category := range Categories {
+		if c >= category.Min && c <= category.Max {
+			return category, true
+		}
+	}
+
+	return Category{}, false
+}
+
+// Severity ranks c, SeverityNone for unknown codes.
+func (c Code) Severity() Severity {
+	return codes[c].severity
+}
+
+// Icon names the icon for c, with -day or -night appended for icons that
+// differ after dark.
+func (c Code) Icon(day bool) string {
+	info, ok := codes[c]
+	switch {
+	case !ok:
+		return "unknown"
+	case !info.dayNight:
+		return info.icon
+	case day:
+		return info.icon + "-day"
+	default:
+		return info.icon + "-night"
+	}
+}
+
+// IsDaytime approximates daylight as 06:00 to 18:00 local time, for
+// observations that don't record whether it was day.
+func IsDaytime(local time.Time) bool {
+	return local.Hour() >= 6 && local.Hour() < 18
+}
+
+// MarshalText writes c as a decimal string, the same as it is stored.
+func (c Code) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Code) UnmarshalText(text []byte) error {
+	n, err := strconv.Atoi(string(text))
+	if err != nil {
+		return fmt.Errorf("%q isn't a WMO weather code", text)
+	}
+
+	*c = Code(n)
+	return nil
+}
+
+// Scan reads a code stored as TEXT or INTEGER.
+func (c *Code) Scan(src any) error {
+	switch src := src.(type) {
+	case int64:
+		*c = Code(src)
+		return nil
+	case string:
+		return c.UnmarshalText([]byte(src))
+	case []byte:
+		return c.UnmarshalText(src)
+	default:
+		return fmt.Errorf("can't scan %T into a weather code", src)
+	}
+}
+
+// Value stores c as TEXT, as observations always have.
+func (c Code) Value() (driver.Value, error) {
+	return c.String(), nil
+}
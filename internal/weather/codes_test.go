@@ -0,0 +1,64 @@
+package weather
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCode(t *testing.T) {
+	t.Run("parses stored codes", func(t *testing.T) {
+		code, err := ParseCode("63")
+		if err != nil || code != 63 {
+			t.Fatalf("expected 63, got %v, %v", code, err)
+		}
+
+		for _, invalid := range []string{"", "rain", "42", "-1"} {
+			if _, err := ParseCode(invalid); err == nil {
+				t.Errorf("expected %q to be rejected", invalid)
+			}
+		}
+	})
+
+	t.Run("describes codes", func(t *testing.T) {
+		code := Code(63)
+		category, ok := code.Category()
+		if code.Description() != "Moderate rain" || !ok || category.Name != "rain" || code.Severity() != SeverityModerate {
+			t.Errorf("expected moderate rain, got %q %v %v", code.Description(), category, code.Severity())
+		}
+
+		if Code(42).Description() != "Unknown weather" || Code(42).Icon(true) != "unknown" {
+			t.Errorf("expected 42 to be unknown")
+		}
+	})
+
+	t.Run("has day and night icons", func(t *testing.T) {
+		if Code(0).Icon(true) != "clear-day" || Code(0).Icon(false) != "clear-night" {
+			t.Errorf("expected clear-day and clear-night, got %q and %q", Code(0).Icon(true), Code(0).Icon(false))
+		}
+		if Code(63).Icon(false) != "rain" {
+			t.Errorf("expected rain, got %q", Code(63).Icon(false))
+		}
+	})
+
+	t.Run("covers every code with a category", func(t *testing.T) {
+		for code := range codes {
+			if _, ok := code.Category(); !ok {
+				t.Errorf("%d has no category", code)
+			}
+		}
+	})
+
+	t.Run("round trips through storage", func(t *testing.T) {
+		value, _ := Code(95).Value()
+
+		var scanned Code
+		if err := scanned.Scan(value); err != nil || scanned != 95 {
+			t.Errorf("expected 95, got %v, %v", scanned, err)
+		}
+
+		encoded, _ := json.Marshal(Code(95))
+		if string(encoded) != `"95"` {
+			t.Errorf(`expected "95", got %s`, encoded)
+		}
+	})
+}
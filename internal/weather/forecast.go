@@ -0,0 +1,300 @@
+package weather
+
+import (
+	"weather/internal/fetch"
+	"weather/internal/validation"
+
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HourlyForecast is the weather expected during one hour.
+type HourlyForecast struct {
+	Time                     time.Time
+	TemperatureC             float64
+	PrecipitationProbability int
+	WeatherCode              Code
+}
+
+// DailyForecast is the weather expected over one day. Date is midnight at
+// the start of the day, in the forecast's timezone.
+type DailyForecast struct {
+	Date                        time.Time
+	TemperatureMinC             float64
+	TemperatureMaxC             float64
+	PrecipitationProbabilityMax int
+	WeatherCode                 Code
+	Sunrise                     time.Time
+	Sunset                      time.Time
+}
+
+// Forecast is the upcoming weather at a location, hour by hour and day by
+// day, with times in the location's timezone.
+type Forecast struct {
+	Timezone string
+	Hourly   []HourlyForecast
+	Daily    []DailyForecast
+}
+
+// HoursFrom returns up to n hours of the forecast, starting with the hour
+// that contains t.
+func (f Forecast) HoursFrom(t time.Time, n int) []HourlyForecast {
+	start := t.Truncate(time.Hour)
+	for i, hour := range f.Hourly {
+		if !hour.Time.Before(start) {
+			return f.Hourly[i:min(i+n, len(f.Hourly))]
+		}
+	}
+
+	return nil
+}
+
+// Day returns the forecast for the day containing t.
+func (f Forecast) Day(t time.Time) (DailyForecast, bool) {
+	for _, day := range f.Daily {
+		if !t.Before(day.Date) && t.Before(day.Date.AddDate(0, 0, 1)) {
+			return day, true
+		}
+	}
+
+	return DailyForecast{}, false
+}
+
+// ForecastProvider fetches the forecast at a location from an upstream
+// weather API.
+type ForecastProvider interface {
+	Forecast(ctx context.Context, lat float64, lon float64) (Forecast, error)
+}
+
+type HourlyUnits struct {
+	Time                     string `json:"time"`
+	Temperature2m            string `json:"temperature_2m"`
+	PrecipitationProbability string `json:"precipitation_probability"`
+	WeatherCode              string `json:"weather_code"`
+}
+
+type Hourly struct {
+	Time                     []string  `json:"time"`
+	Temperature2m            []float64 `json:"temperature_2m"`
+	PrecipitationProbability []int     `json:"precipitation_probability"`
+	WeatherCode              []int     `json:"weather_code"`
+}
+
+type DailyUnits struct {
+	Time                        string `json:"time"`
+	WeatherCode                 string `json:"weather_code"`
+	Temperature2mMax            string `json:"temperature_2m_max"`
+	Temperature2mMin            string `json:"temperature_2m_min"`
+	PrecipitationProbabilityMax string `json:"precipitation_probability_max"`
+	Sunrise                     string `json:"sunrise"`
+	Sunset                      string `json:"sunset"`
+}
+
+type Daily struct {
+	Time                        []string  `json:"time"`
+	WeatherCode                 []int     `json:"weather_code"`
+	Temperature2mMax            []float64 `json:"temperature_2m_max"`
+	Temperature2mMin            []float64 `json:"temperature_2m_min"`
+	PrecipitationProbabilityMax []int     `json:"precipitation_probability_max"`
+	Sunrise                     []string  `json:"sunrise"`
+	Sunset                      []string  `json:"sunset"`
+}
+
+type OpenMeteoForecast struct {
+	Latitude             float64     `json:"latitude"`
+	Longitude            float64     `json:"longitude"`
+	GenerationTimeMs     float64     `json:"generation_time_ms"`
+	UTCOffsetSeconds     int         `json:"utc_offset_seconds"`
+	Timezone             string      `json:"timezone"`
+	TimezoneAbbreviation string      `json:"timezone_abbreviation"`
+	Elevation            float64     `json:"elevation"`
+	HourlyUnits          HourlyUnits `json:"hourly_units"`
+	Hourly               Hourly      `json:"hourly"`
+	DailyUnits           DailyUnits  `json:"daily_units"`
+	Daily                Daily       `json:"daily"`
+}
+
+const hourlyFields = "temperature_2m,precipitation_probability,weather_code"
+const dailyFields = "weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max,sunrise,sunset"
+
+// forecastDays is how far ahead forecasts are requested.
+const forecastDays = 7
+
+// openMeteoDateLayout is how Open-Meteo formats daily forecast dates.
+const openMeteoDateLayout = "2006-01-02"
+
+func (w OpenMeteoForecast) Validate() (validation.ValidationProblems, error) {
+	v := validation.Validator{}
+
+	validation.Field(&v, "latitude", w.Latitude, validation.Between(-90.0, 90.0))
+	validation.Field(&v, "longitude", w.Longitude, validation.Between(-180.0, 180.0))
+	validation.Field(&v, "timezone", w.Timezone, validation.Timezone)
+
+	validation.Field(&v, "hourly_units.time", w.HourlyUnits.Time, validation.OneOf("iso8601"))
+	validation.Field(&v, "hourly_units.weather_code", w.HourlyUnits.WeatherCode, validation.OneOf("wmo code"))
+	readUnit(&v, "hourly_units.precipitation_probability", w.HourlyUnits.PrecipitationProbability, DimensionRatio)
+	hourlyTemperature, _ := readUnit(&v, "hourly_units.temperature_2m", w.HourlyUnits.Temperature2m, DimensionTemperature)
+
+	validation.Field(&v, "daily_units.time", w.DailyUnits.Time, validation.OneOf("iso8601"))
+	validation.Field(&v, "daily_units.weather_code", w.DailyUnits.WeatherCode, validation.OneOf("wmo code"))
+	validation.Field(&v, "daily_units.sunrise", w.DailyUnits.Sunrise, validation.OneOf("iso8601"))
+	validation.Field(&v, "daily_units.sunset", w.DailyUnits.Sunset, validation.OneOf("iso8601"))
+	readUnit(&v, "daily_units.precipitation_probability_max", w.DailyUnits.PrecipitationProbabilityMax, DimensionRatio)
+	dailyMax, _ := readUnit(&v, "daily_units.temperature_2m_max", w.DailyUnits.Temperature2mMax, DimensionTemperature)
+	dailyMin, _ := readUnit(&v, "daily_units.temperature_2m_min", w.DailyUnits.Temperature2mMin, DimensionTemperature)
+
+	// Each series is a column of the same table, so they must line up.
+	hours := len(w.Hourly.Time)
+	if !sameLength(&v, hours, map[string]int{
+		"hourly.temperature_2m":            len(w.Hourly.Temperature2m),
+		"hourly.precipitation_probability": len(w.Hourly.PrecipitationProbability),
+		"hourly.weather_code":              len(w.Hourly.WeatherCode),
+	}) {
+		hours = 0
+	}
+
+	days := len(w.Daily.Time)
+	if !sameLength(&v, days, map[string]int{
+		"daily.weather_code":                  len(w.Daily.WeatherCode),
+		"daily.temperature_2m_max":            len(w.Daily.Temperature2mMax),
+		"daily.temperature_2m_min":            len(w.Daily.Temperature2mMin),
+		"daily.precipitation_probability_max": len(w.Daily.PrecipitationProbabilityMax),
+		"daily.sunrise":                       len(w.Daily.Sunrise),
+		"daily.sunset":                        len(w.Daily.Sunset),
+	}) {
+		days = 0
+	}
+
+	for i := range hours {
+		if _, err := time.Parse(openMeteoTimeLayout, w.Hourly.Time[i]); err != nil {
+			v.Problem("hourly.time", "%q isn't a time", w.Hourly.Time[i])
+		}
+		if hourlyTemperature != "" {
+			temperature, _ := TemperatureIn(w.Hourly.Temperature2m[i], hourlyTemperature)
+			validation.Field(&v, "hourly.temperature_2m", temperature.Celsius(), validation.Between(-90.0, 60.0))
+		}
+		validation.Field(&v, "hourly.precipitation_probability", w.Hourly.PrecipitationProbability[i], validation.Between(0, 100))
+		if !Code(w.Hourly.WeatherCode[i]).Valid() {
+			v.Problem("hourly.weather_code", "%d isn't a WMO weather code", w.Hourly.WeatherCode[i])
+		}
+	}
+
+	for i := range days {
+		if _, err := time.Parse(openMeteoDateLayout, w.Daily.Time[i]); err != nil {
+			v.Problem("daily.time", "%q isn't a date", w.Daily.Time[i])
+		}
+		if !Code(w.Daily.WeatherCode[i]).Valid() {
+			v.Problem("daily.weather_code", "%d isn't a WMO weather code", w.Daily.WeatherCode[i])
+		}
+		if dailyMax != "" && dailyMin != "" {
+			high, _ := TemperatureIn(w.Daily.Temperature2mMax[i], dailyMax)
+			low, _ := TemperatureIn(w.Daily.Temperature2mMin[i], dailyMin)
+			validation.Field(&v, "daily.temperature_2m_max", high.Celsius(), validation.Between(-90.0, 60.0))
+			validation.Field(&v, "daily.temperature_2m_min", low.Celsius(), validation.Between(-90.0, high.Celsius()))
+		}
+		validation.Field(&v, "daily.precipitation_probability_max", w.Daily.PrecipitationProbabilityMax[i], validation.Between(0, 100))
+		// Sunrise and sunset are the start of the day during polar night and
+		// midnight sun, but always times.
+		if _, err := time.Parse(openMeteoTimeLayout, w.Daily.Sunrise[i]); err != nil {
+			v.Problem("daily.sunrise", "%q isn't a time", w.Daily.Sunrise[i])
+		}
+		if _, err := time.Parse(openMeteoTimeLayout, w.Daily.Sunset[i]); err != nil {
+			v.Problem("daily.sunset", "%q isn't a time", w.Daily.Sunset[i])
+		}
+	}
+
+	return v.Validate()
+}
+
+// sameLength reports whether every column has n values, adding a problem for
+// each that doesn't.
+func sameLength(v *validation.Validator, n int, columns map[string]int) bool {
+	ok := true
+	for field, length := range columns {
+		if length != n {
+			v.Problem(field, "has %d values, expected %d", length, n)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// Forecast converts w, which Validate has checked, to the units observations
+// are stored in, placing its times in the timezone Open-Meteo resolved for
+// the location.
+func (w OpenMeteoForecast) Forecast() Forecast {
+	tzloc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		tzloc = time.UTC
+	}
+	parse := func(layout string, value string) time.Time {
+		t, _ := time.ParseInLocation(layout, value, tzloc)
+		return t
+	}
+
+	celsius := func(value float64, unit string) float64 {
+		temperature, _ := TemperatureIn(value, Unit(unit))
+		return temperature.Celsius()
+	}
+
+	forecast := Forecast{
+		Timezone: w.Timezone,
+		Hourly:   make([]HourlyForecast, len(w.Hourly.Time)),
+		Daily:    make([]DailyForecast, len(w.Daily.Time)),
+	}
+
+	for i := range w.Hourly.Time {
+		forecast.Hourly[i] = HourlyForecast{
+			Time:                     parse(openMeteoTimeLayout, w.Hourly.Time[i]),
+			TemperatureC:             celsius(w.Hourly.Temperature2m[i], w.HourlyUnits.Temperature2m),
+			PrecipitationProbability: w.Hourly.PrecipitationProbability[i],
+			WeatherCode:              Code(w.Hourly.WeatherCode[i]),
+		}
+	}
+
+	for i := range w.Daily.Time {
+		forecast.Daily[i] = DailyForecast{
+			Date:                        parse(openMeteoDateLayout, w.Daily.Time[i]),
+			TemperatureMinC:             celsius(w.Daily.Temperature2mMin[i], w.DailyUnits.Temperature2mMin),
+			TemperatureMaxC:             celsius(w.Daily.Temperature2mMax[i], w.DailyUnits.Temperature2mMax),
+			PrecipitationProbabilityMax: w.Daily.PrecipitationProbabilityMax[i],
+			WeatherCode:                 Code(w.Daily.WeatherCode[i]),
+			Sunrise:                     parse(openMeteoTimeLayout, w.Daily.Sunrise[i]),
+			Sunset:                      parse(openMeteoTimeLayout, w.Daily.Sunset[i]),
+		}
+	}
+
+	return forecast
+}
+
+func (p OpenMeteo) Forecast(ctx context.Context, lat float64, lon float64) (Forecast, error) {
+	forecast, err := forecastForLatLon(ctx, p.Client, p.BasePath, lat, lon)
+	if err != nil {
+		return Forecast{}, err
+	}
+
+	return forecast.Forecast(), nil
+}
+
+func ForecastForLatLon(ctx context.Context, lat float64, lon float64) (OpenMeteoForecast, error) {
+	return forecastForLatLon(ctx, nil, OpenMeteoBasePath, lat, lon)
+}
+
+func forecastForLatLon(ctx context.Context, client *http.Client, base string, lat float64, lon float64) (OpenMeteoForecast, error) {
+	forecast := OpenMeteoForecast{}
+
+	// Daily values are aggregated over local days, so the location's own
+	// timezone is requested rather than GMT.
+	endpoint := fmt.Sprintf("%s?hourly=%s&daily=%s&timezone=auto&forecast_days=%d&latitude=%.2f&longitude=%.2f",
+		base, hourlyFields, dailyFields, forecastDays, lat, lon,
+	)
+
+	if err := fetch.JSON(ctx, client, endpoint, &forecast); err != nil {
+		return forecast, fmt.Errorf("OpenMeteo API error %w", err)
+	}
+
+	return forecast, nil
+}
@@ -0,0 +1,102 @@
+package weather
+
+import (
+	"weather/internal/fetch"
+
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const openMeteoForecastFixture = `{
+  "latitude": 52.52,
+  "longitude": 13.419998,
+  "utc_offset_seconds": 3600,
+  "timezone": "Europe/Berlin",
+  "timezone_abbreviation": "CET",
+  "hourly_units": {
+    "time": "iso8601",
+    "temperature_2m": "°C",
+    "precipitation_probability": "%",
+    "weather_code": "wmo code"
+  },
+  "hourly": {
+    "time": ["2024-11-04T00:00", "2024-11-04T01:00", "2024-11-04T02:00"],
+    "temperature_2m": [6.1, 5.8, 5.2],
+    "precipitation_probability": [10, 20, 35],
+    "weather_code": [3, 3, 61]
+  },
+  "daily_units": {
+    "time": "iso8601",
+    "weather_code": "wmo code",
+    "temperature_2m_max": "°C",
+    "temperature_2m_min": "°C",
+    "precipitation_probability_max": "%",
+    "sunrise": "iso8601",
+    "sunset": "iso8601"
+  },
+  "daily": {
+    "time": ["2024-11-04"],
+    "weather_code": [61],
+    "temperature_2m_max": [9.4],
+    "temperature_2m_min": [5.2],
+    "precipitation_probability_max": [35],
+    "sunrise": ["2024-11-04T07:06"],
+    "sunset": ["2024-11-04T16:22"]
+  }
+}`
+
+func TestOpenMeteoForecast(t *testing.T) {
+	serve := func(body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+	}
+
+	t.Run("converts to local times", func(t *testing.T) {
+		server := serve(openMeteoForecastFixture)
+		defer server.Close()
+
+		forecast, err := OpenMeteo{BasePath: server.URL}.Forecast(context.Background(), 52.52, 13.42)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		berlin, _ := time.LoadLocation("Europe/Berlin")
+		if len(forecast.Hourly) != 3 || len(forecast.Daily) != 1 {
+			t.Fatalf("expected 3 hours and 1 day, got %+v", forecast)
+		}
+		if want := time.Date(2024, 11, 4, 1, 0, 0, 0, berlin); !forecast.Hourly[1].Time.Equal(want) {
+			t.Errorf("expected %v, got %v", want, forecast.Hourly[1].Time)
+		}
+
+		hours := forecast.HoursFrom(time.Date(2024, 11, 4, 1, 30, 0, 0, berlin), 24)
+		if len(hours) != 2 || hours[0].TemperatureC != 5.8 {
+			t.Errorf("expected the hours from 01:00, got %+v", hours)
+		}
+
+		day, ok := forecast.Day(time.Date(2024, 11, 4, 23, 0, 0, 0, berlin))
+		if !ok || day.Sunset.Hour() != 16 {
+			t.Errorf("expected 2024-11-04 with sunset at 16:22, got %+v", day)
+		}
+	})
+
+	t.Run("surfaces misaligned series", func(t *testing.T) {
+		server := serve(strings.Replace(openMeteoForecastFixture, "[10, 20, 35]", "[10, 20]", 1))
+		defer server.Close()
+
+		_, err := OpenMeteo{BasePath: server.URL}.Forecast(context.Background(), 52.52, 13.42)
+
+		invalid := &fetch.ValidationError{}
+		if !errors.As(err, &invalid) {
+			t.Fatalf("expected a ValidationError, got %v", err)
+		}
+		if _, ok := invalid.Problems["hourly.precipitation_probability"]; !ok || len(invalid.Problems) != 1 {
+			t.Errorf("expected a problem with hourly.precipitation_probability, got %v", invalid.Problems)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package weather
+
+import (
+	"weather/internal/fetch"
+	"weather/internal/validation"
+
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type METNorwayDetails struct {
+	AirTemperature      float64  `json:"air_temperature"`
+	RelativeHumidity    float64  `json:"relative_humidity"`
+	PrecipitationAmount float64  `json:"precipitation_amount"`
+	CloudAreaFraction   *float64 `json:"cloud_area_fraction"`
+	WindFromDirection   *float64 `json:"wind_from_direction"`
+	WindSpeed           *float64 `json:"wind_speed"`
+}
+
+type METNorwayPeriod struct {
+	Summary struct {
+		SymbolCode string `json:"symbol_code"`
+	} `json:"summary"`
+	Details METNorwayDetails `json:"details"`
+}
+
+type METNorwayTimestep struct {
+	Time string `json:"time"`
+	Data struct {
+		Instant struct {
+			Details METNorwayDetails `json:"details"`
+		} `json:"instant"`
+		Next1Hours *METNorwayPeriod `json:"next_1_hours"`
+		Next6Hours *METNorwayPeriod `json:"next_6_hours"`
+	} `json:"data"`
+}
+
+type METNorwayWeather struct {
+	Properties struct {
+		Timeseries []METNorwayTimestep `json:"timeseries"`
+	} `json:"properties"`
+}
+
+func (w METNorwayWeather) Validate() (validation.ValidationProblems, error) {
+	if len(w.Properties.Timeseries) == 0 {
+		return validation.ValidationProblems{
+			"properties.timeseries": "no timesteps returned",
+		}, validation.ErrValidation
+	}
+
+	return nil, nil
+}
+
+const METNorwayBasePath = "https://api.met.no/weatherapi/locationforecast/2.0/compact"
+
+// METNorway is a Provider backed by the MET Norway locationforecast API.
+type METNorway struct {
+	BasePath string
+	Client   *http.Client
+}
+
+func (p METNorway) Current(ctx context.Context, lat float64, lon float64) (Conditions, error) {
+	weather := METNorwayWeather{}
+
+	// MET Norway asks that coordinates use at most 4 decimals, we use
+	// Open-Meteo's precision so both providers share cache keys.
+	endpoint := fmt.Sprintf("%s?lat=%.2f&lon=%.2f", p.BasePath, lat, lon)
+
+	if err := fetch.JSON(ctx, p.Client, endpoint, &weather); err != nil {
+		return Conditions{}, fmt.Errorf("MET Norway API error %w", err)
+	}
+
+	step := weather.Properties.Timeseries[0]
+
+	observed, err := time.Parse(time.RFC3339, step.Time)
+	if err != nil {
+		observed = time.Now().UTC()
+	}
+
+	period := step.Data.Next1Hours
+	if period == nil {
+		period = step.Data.Next6Hours
+	}
+
+	instant := step.Data.Instant.Details
+	conditions := Conditions{
+		TemperatureC:     instant.AirTemperature,
+		RelativeHumidity: instant.RelativeHumidity,
+		Time:             observed,
+		CloudCover:       instant.CloudAreaFraction,
+		WindDirection:    instant.WindFromDirection,
+	}
+	// The compact forecast only gives sea level pressure, which isn't the
+	// surface pressure anywhere but the coast, so pressure is left unknown.
+	if instant.WindSpeed != nil {
+		speed, _ := SpeedIn(*instant.WindSpeed, UnitMetresPerSecond)
+		conditions.WindSpeedKMH = known(speed.KilometresPerHour())
+	}
+
+	if period != nil {
+		symbol := period.Summary.SymbolCode
+		conditions.WeatherCode = symbolToWMO(symbol)
+		conditions.PrecipitationMM = known(period.Details.PrecipitationAmount)
+
+		if strings.Contains(symbol, "snow") {
+			conditions.SnowfallCM = period.Details.PrecipitationAmount * snowCMPerWaterMM
+		} else {
+			conditions.RainMM = period.Details.PrecipitationAmount
+		}
+	}
+
+	return conditions, nil
+}
+
+// snowCMPerWaterMM converts liquid-equivalent precipitation to snow depth
+// with the same 7:10 ratio Open-Meteo uses for its snowfall field.
+const snowCMPerWaterMM = 0.7
+
+// symbolToWMO maps MET Norway symbol codes onto the closest WMO weather code.
+//
+// https://api.met.no/weatherapi/weathericon/2.0/documentation
+func symbolToWMO(symbol string) Code {
+	symbol, _, _ = strings.Cut(symbol, "_")
+
+	if strings.Contains(symbol, "thunder") {
+		return 95
+	}
+
+	switch symbol {
+	case "clearsky":
+		return 0
+	case "fair":
+		return 1
+	case "partlycloudy":
+		return 2
+	case "cloudy":
+		return 3
+	case "fog":
+		return 45
+	case "lightrain":
+		return 61
+	case "rain":
+		return 63
+	case "heavyrain":
+		return 65
+	case "lightsleet", "sleet", "lightsleetshowers", "sleetshowers":
+		return 66
+	case "heavysleet", "heavysleetshowers":
+		return 67
+	case "lightsnow":
+		return 71
+	case "snow":
+		return 73
+	case "heavysnow":
+		return 75
+	case "lightrainshowers":
+		return 80
+	case "rainshowers":
+		return 81
+	case "heavyrainshowers":
+		return 82
+	case "lightsnowshowers", "snowshowers":
+		return 85
+	case "heavysnowshowers":
+		return 86
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,91 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const metNorwayFixture = `{
+  "properties": {
+    "timeseries": [
+      {
+        "time": "2024-11-04T12:00:00Z",
+        "data": {
+          "instant": {"details": {"air_temperature": 4.2, "relative_humidity": 81.5}},
+          "next_1_hours": {
+            "summary": {"symbol_code": "lightsnowshowers_day"},
+            "details": {"precipitation_amount": 1.0}
+          }
+        }
+      }
+    ]
+  }
+}`
+
+func TestMETNorway(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(metNorwayFixture))
+	}))
+	defer server.Close()
+
+	conditions, err := METNorway{BasePath: server.URL}.Current(context.Background(), 59.91, 10.75)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	t.Run("normalizes conditions", func(t *testing.T) {
+		if conditions.TemperatureC != 4.2 || conditions.RelativeHumidity != 81.5 {
+			t.Errorf("unexpected conditions: %+v", conditions)
+		}
+		if conditions.WeatherCode != 85 || conditions.SnowfallCM != 0.7 || conditions.RainMM != 0 {
+			t.Errorf("unexpected precipitation: %+v", conditions)
+		}
+	})
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Current(ctx context.Context, lat float64, lon float64) (Conditions, error) {
+	return Conditions{}, errors.New("upstream is down")
+}
+
+type fixedProvider struct{ conditions Conditions }
+
+func (p fixedProvider) Current(ctx context.Context, lat float64, lon float64) (Conditions, error) {
+	return p.conditions, nil
+}
+
+func (p fixedProvider) Forecast(ctx context.Context, lat float64, lon float64) (Forecast, error) {
+	return Forecast{Timezone: "UTC"}, nil
+}
+
+func TestChain(t *testing.T) {
+	want := Conditions{TemperatureC: 12}
+
+	t.Run("falls back to the next provider", func(t *testing.T) {
+		got, err := Chain{failingProvider{}, fixedProvider{want}}.Current(context.Background(), 0, 0)
+		if err != nil || got != want {
+			t.Errorf("expected %+v, got %+v (%v)", want, got, err)
+		}
+	})
+
+	t.Run("fails when every provider fails", func(t *testing.T) {
+		if _, err := (Chain{failingProvider{}}).Current(context.Background(), 0, 0); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("forecasts with providers that can", func(t *testing.T) {
+		forecast, err := Chain{failingProvider{}, fixedProvider{want}}.Forecast(context.Background(), 0, 0)
+		if err != nil || forecast.Timezone != "UTC" {
+			t.Errorf("expected the fixed provider's forecast, got %+v (%v)", forecast, err)
+		}
+
+		if _, err := (Chain{failingProvider{}}).Forecast(context.Background(), 0, 0); !errors.Is(err, ErrNoForecast) {
+			t.Errorf("expected ErrNoForecast, got %v", err)
+		}
+	})
+}
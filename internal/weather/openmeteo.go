@@ -4,27 +4,48 @@ import (
 	"weather/internal/fetch"
 	"weather/internal/validation"
 
+	"context"
 	"fmt"
+	"net/http"
+	"time"
 )
 
 type CurrentUnits struct {
-	Time               string `json:"time"`
-	Interval           string `json:"interval"`
-	Temperature2m      string `json:"temperature_2m"`
-	RelativeHumidity2m string `json:"relative_humidity_2m"`
-	Rain               string `json:"rain"`
-	Snowfall           string `json:"snowfall"`
-	WeatherCode        string `json:"weather_code"`
+	Time                string `json:"time"`
+	Interval            string `json:"interval"`
+	Temperature2m       string `json:"temperature_2m"`
+	RelativeHumidity2m  string `json:"relative_humidity_2m"`
+	ApparentTemperature string `json:"apparent_temperature"`
+	IsDay               string `json:"is_day"`
+	Precipitation       string `json:"precipitation"`
+	Rain                string `json:"rain"`
+	Snowfall            string `json:"snowfall"`
+	WeatherCode         string `json:"weather_code"`
+	CloudCover          string `json:"cloud_cover"`
+	SurfacePressure     string `json:"surface_pressure"`
+	WindSpeed10m        string `json:"wind_speed_10m"`
+	WindDirection10m    string `json:"wind_direction_10m"`
+	WindGusts10m        string `json:"wind_gusts_10m"`
+	UVIndex             string `json:"uv_index"`
 }
 
 type Current struct {
-	Time               string  `json:"time"`
-	Interval           int     `json:"interval"`
-	Temperature2m      float64 `json:"temperature_2m"`
-	RelativeHumidity2m int     `json:"relative_humidity_2m"`
-	Rain               float64 `json:"rain"`
-	Snowfall           float64 `json:"snowfall"`
-	WeatherCode        int     `json:"weather_code"`
+	Time                string  `json:"time"`
+	Interval            int     `json:"interval"`
+	Temperature2m       float64 `json:"temperature_2m"`
+	RelativeHumidity2m  int     `json:"relative_humidity_2m"`
+	ApparentTemperature float64 `json:"apparent_temperature"`
+	IsDay               int     `json:"is_day"`
+	Precipitation       float64 `json:"precipitation"`
+	Rain                float64 `json:"rain"`
+	Snowfall            float64 `json:"snowfall"`
+	WeatherCode         int     `json:"weather_code"`
+	CloudCover          int     `json:"cloud_cover"`
+	SurfacePressure     float64 `json:"surface_pressure"`
+	WindSpeed10m        float64 `json:"wind_speed_10m"`
+	WindDirection10m    int     `json:"wind_direction_10m"`
+	WindGusts10m        float64 `json:"wind_gusts_10m"`
+	UVIndex             float64 `json:"uv_index"`
 }
 
 type OpenMeteoWeather struct {
@@ -39,42 +60,158 @@ type OpenMeteoWeather struct {
 	Current              Current      `json:"current"`
 }
 
+// readUnit parses the unit Open-Meteo labelled field with, recording a
+// problem with v unless it measures dimension.
+func readUnit(v *validation.Validator, field string, label string, dimension Dimension) (Unit, bool) {
+	unit, err := ParseUnit(label, dimension)
+	if err != nil {
+		v.Problem(field, "%v", err)
+		return "", false
+	}
+
+	return unit, true
+}
+
 func (w OpenMeteoWeather) Validate() (validation.ValidationProblems, error) {
-	// TODO
-	return nil, nil
+	v := validation.Validator{}
+
+	validation.Field(&v, "latitude", w.Latitude, validation.Between(-90.0, 90.0))
+	validation.Field(&v, "longitude", w.Longitude, validation.Between(-180.0, 180.0))
+	validation.Field(&v, "timezone", w.Timezone, validation.Timezone)
+
+	validation.Field(&v, "current_units.time", w.CurrentUnits.Time, validation.OneOf("iso8601"))
+	validation.Field(&v, "current_units.interval", w.CurrentUnits.Interval, validation.OneOf("seconds"))
+	validation.Field(&v, "current_units.weather_code", w.CurrentUnits.WeatherCode, validation.OneOf("wmo code"))
+	validation.Field(&v, "current_units.is_day", w.CurrentUnits.IsDay, validation.OneOf(""))
+	validation.Field(&v, "current_units.uv_index", w.CurrentUnits.UVIndex, validation.OneOf(""))
+	readUnit(&v, "current_units.relative_humidity_2m", w.CurrentUnits.RelativeHumidity2m, DimensionRatio)
+	readUnit(&v, "current_units.cloud_cover", w.CurrentUnits.CloudCover, DimensionRatio)
+	readUnit(&v, "current_units.wind_direction_10m", w.CurrentUnits.WindDirection10m, DimensionAngle)
+	readUnit(&v, "current_units.surface_pressure", w.CurrentUnits.SurfacePressure, DimensionPressure)
+
+	if _, err := time.Parse(openMeteoTimeLayout, w.Current.Time); err != nil {
+		v.Problem("current.time", "%q isn't a time", w.Current.Time)
+	}
+	// Measurements are checked in the units they are stored in, whichever
+	// units Open-Meteo sent them in. The coldest and hottest temperatures
+	// ever recorded are -89.2°C and 56.7°C.
+	if unit, ok := readUnit(&v, "current_units.temperature_2m", w.CurrentUnits.Temperature2m, DimensionTemperature); ok {
+		temperature, _ := TemperatureIn(w.Current.Temperature2m, unit)
+		validation.Field(&v, "current.temperature_2m", temperature.Celsius(), validation.Between(-90.0, 60.0))
+	}
+	// Wind chill and humidity stretch how temperatures feel well past the
+	// records.
+	if unit, ok := readUnit(&v, "current_units.apparent_temperature", w.CurrentUnits.ApparentTemperature, DimensionTemperature); ok {
+		temperature, _ := TemperatureIn(w.Current.ApparentTemperature, unit)
+		validation.Field(&v, "current.apparent_temperature", temperature.Celsius(), validation.Between(-120.0, 80.0))
+	}
+	validation.Field(&v, "current.is_day", w.Current.IsDay, validation.OneOf(0, 1))
+	validation.Field(&v, "current.relative_humidity_2m", w.Current.RelativeHumidity2m, validation.Between(0, 100))
+	if _, ok := readUnit(&v, "current_units.rain", w.CurrentUnits.Rain, DimensionLength); ok {
+		validation.Field(&v, "current.rain", w.Current.Rain, validation.AtLeast(0.0))
+	}
+	if _, ok := readUnit(&v, "current_units.snowfall", w.CurrentUnits.Snowfall, DimensionLength); ok {
+		validation.Field(&v, "current.snowfall", w.Current.Snowfall, validation.AtLeast(0.0))
+	}
+	if _, ok := readUnit(&v, "current_units.precipitation", w.CurrentUnits.Precipitation, DimensionLength); ok {
+		validation.Field(&v, "current.precipitation", w.Current.Precipitation, validation.AtLeast(0.0))
+	}
+	validation.Field(&v, "current.cloud_cover", w.Current.CloudCover, validation.Between(0, 100))
+	// The lowest surface pressure, on Everest, is around 300 hPa.
+	validation.Field(&v, "current.surface_pressure", w.Current.SurfacePressure, validation.Between(250.0, 1100.0))
+	if _, ok := readUnit(&v, "current_units.wind_speed_10m", w.CurrentUnits.WindSpeed10m, DimensionSpeed); ok {
+		validation.Field(&v, "current.wind_speed_10m", w.Current.WindSpeed10m, validation.AtLeast(0.0))
+	}
+	if _, ok := readUnit(&v, "current_units.wind_gusts_10m", w.CurrentUnits.WindGusts10m, DimensionSpeed); ok {
+		validation.Field(&v, "current.wind_gusts_10m", w.Current.WindGusts10m, validation.AtLeast(0.0))
+	}
+	validation.Field(&v, "current.wind_direction_10m", w.Current.WindDirection10m, validation.Between(0, 360))
+	validation.Field(&v, "current.uv_index", w.Current.UVIndex, validation.AtLeast(0.0))
+	if !Code(w.Current.WeatherCode).Valid() {
+		v.Problem("current.weather_code", "%d isn't a WMO weather code", w.Current.WeatherCode)
+	}
+
+	return v.Validate()
 }
 
-const basePath = "https://api.open-meteo.com/v1/forecast"
-const fields = "temperature_2m,relative_humidity_2m,rain,snowfall,weather_code"
+// Conditions converts w, which Validate has checked, to the units
+// observations are stored in.
+func (w OpenMeteoWeather) Conditions() Conditions {
+	observed, err := time.Parse(openMeteoTimeLayout, w.Current.Time)
+	if err != nil {
+		observed = time.Now().UTC()
+	}
 
-func ForLatLon(lat float64, lon float64) (OpenMeteoWeather, error) {
+	temperature, _ := TemperatureIn(w.Current.Temperature2m, Unit(w.CurrentUnits.Temperature2m))
+	rain, _ := DepthIn(w.Current.Rain, Unit(w.CurrentUnits.Rain))
+	snowfall, _ := SnowfallIn(w.Current.Snowfall, Unit(w.CurrentUnits.Snowfall))
+	apparent, _ := TemperatureIn(w.Current.ApparentTemperature, Unit(w.CurrentUnits.ApparentTemperature))
+	precipitation, _ := DepthIn(w.Current.Precipitation, Unit(w.CurrentUnits.Precipitation))
+	windSpeed, _ := SpeedIn(w.Current.WindSpeed10m, Unit(w.CurrentUnits.WindSpeed10m))
+	windGusts, _ := SpeedIn(w.Current.WindGusts10m, Unit(w.CurrentUnits.WindGusts10m))
+
+	return Conditions{
+		TemperatureC:         temperature.Celsius(),
+		RelativeHumidity:     float64(w.Current.RelativeHumidity2m),
+		RainMM:               rain.Millimetres(),
+		SnowfallCM:           snowfall.Centimetres(),
+		WeatherCode:          Code(w.Current.WeatherCode),
+		Time:                 observed,
+		ApparentTemperatureC: known(apparent.Celsius()),
+		PrecipitationMM:      known(precipitation.Millimetres()),
+		WindSpeedKMH:         known(windSpeed.KilometresPerHour()),
+		WindDirection:        known(float64(w.Current.WindDirection10m)),
+		WindGustsKMH:         known(windGusts.KilometresPerHour()),
+		SurfacePressureHPA:   known(w.Current.SurfacePressure),
+		CloudCover:           known(float64(w.Current.CloudCover)),
+		UVIndex:              known(w.Current.UVIndex),
+		IsDay:                known(w.Current.IsDay == 1),
+	}
+}
+
+// known points to v, for the optional fields of Conditions.
+func known[T any](v T) *T {
+	return &v
+}
+
+const OpenMeteoBasePath = "https://api.open-meteo.com/v1/forecast"
+const fields = "temperature_2m,relative_humidity_2m,apparent_temperature,is_day," +
+	"precipitation,rain,snowfall,weather_code,cloud_cover,surface_pressure," +
+	"wind_speed_10m,wind_direction_10m,wind_gusts_10m,uv_index"
+
+// openMeteoTimeLayout is how Open-Meteo formats times, in GMT unless a
+// timezone is requested.
+const openMeteoTimeLayout = "2006-01-02T15:04"
+
+// OpenMeteo is a Provider backed by the Open-Meteo forecast API.
+type OpenMeteo struct {
+	BasePath string
+	Client   *http.Client
+}
+
+func (p OpenMeteo) Current(ctx context.Context, lat float64, lon float64) (Conditions, error) {
+	wth, err := forLatLon(ctx, p.Client, p.BasePath, lat, lon)
+	if err != nil {
+		return Conditions{}, err
+	}
+
+	return wth.Conditions(), nil
+}
+
+func ForLatLon(ctx context.Context, lat float64, lon float64) (OpenMeteoWeather, error) {
+	return forLatLon(ctx, nil, OpenMeteoBasePath, lat, lon)
+}
+
+func forLatLon(ctx context.Context, client *http.Client, base string, lat float64, lon float64) (OpenMeteoWeather, error) {
 	weather := OpenMeteoWeather{}
 
 	endpoint := fmt.Sprintf("%s?current=%s&latitude=%.2f&longitude=%.2f",
-		basePath, fields, lat, lon,
+		base, fields, lat, lon,
 	)
 
-	if err := fetch.JSON(endpoint, &weather); err != nil {
+	if err := fetch.JSON(ctx, client, endpoint, &weather); err != nil {
 		return weather, fmt.Errorf("OpenMeteo API error %w", err)
 	}
 
 	return weather, nil
 }
-
-// https://open-meteo.com/en/docs
-//
-// WMO Weather interpretation codes (WW)
-// Code 	Description
-// 0 	Clear sky
-// 1, 2, 3 	Mainly clear, partly cloudy, and overcast
-// 45, 48 	Fog and depositing rime fog
-// 51, 53, 55 	Drizzle: Light, moderate, and dense intensity
-// 56, 57 	Freezing Drizzle: Light and dense intensity
-// 61, 63, 65 	Rain: Slight, moderate and heavy intensity
-// 66, 67 	Freezing Rain: Light and heavy intensity
-// 71, 73, 75 	Snow fall: Slight, moderate, and heavy intensity
-// 77 	Snow grains
-// 80, 81, 82 	Rain showers: Slight, moderate, and violent
-// 85, 86 	Snow showers slight and heavy
-// 95 * 	Thunderstorm: Slight or moderate
-// 96, 99 * 	Thunderstorm with slight and heavy hail
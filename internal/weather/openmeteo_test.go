@@ -1,6 +1,13 @@
 package weather
 
 import (
+	"weather/internal/fetch"
+
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -9,8 +16,160 @@ func Test(t *testing.T) {
 	const lon float64 = 167.733333
 
 	t.Run("runs successfully", func(t *testing.T) {
-		if _, err := ForLatLon(lat, lon); err != nil {
+		if _, err := ForLatLon(context.Background(), lat, lon); err != nil {
 			t.Errorf("%v", err.Error())
 		}
 	})
 }
+
+const openMeteoFixture = `{
+  "latitude": 52.52,
+  "longitude": 13.419998,
+  "generationtime_ms": 0.02,
+  "utc_offset_seconds": 0,
+  "timezone": "GMT",
+  "timezone_abbreviation": "GMT",
+  "elevation": 38.0,
+  "current_units": {
+    "time": "iso8601",
+    "interval": "seconds",
+    "temperature_2m": "°C",
+    "relative_humidity_2m": "%",
+    "apparent_temperature": "°C",
+    "is_day": "",
+    "precipitation": "mm",
+    "rain": "mm",
+    "snowfall": "cm",
+    "weather_code": "wmo code",
+    "cloud_cover": "%",
+    "surface_pressure": "hPa",
+    "wind_speed_10m": "km/h",
+    "wind_direction_10m": "°",
+    "wind_gusts_10m": "km/h",
+    "uv_index": ""
+  },
+  "current": {
+    "time": "2024-11-04T12:00",
+    "interval": 900,
+    "temperature_2m": 71.5,
+    "relative_humidity_2m": 140,
+    "apparent_temperature": 70.0,
+    "is_day": 1,
+    "precipitation": 0.0,
+    "rain": 0.0,
+    "snowfall": 0.0,
+    "weather_code": 42,
+    "cloud_cover": 100,
+    "surface_pressure": 1003.2,
+    "wind_speed_10m": 12.0,
+    "wind_direction_10m": 225,
+    "wind_gusts_10m": 30.2,
+    "uv_index": 0.5
+  }
+}`
+
+// openMeteoImperialFixture is valid, in the units Open-Meteo uses for
+// temperature_unit=fahrenheit, wind_speed_unit=mph and
+// precipitation_unit=inch.
+const openMeteoImperialFixture = `{
+  "latitude": 40.71,
+  "longitude": -74.01,
+  "timezone": "GMT",
+  "current_units": {
+    "time": "iso8601",
+    "interval": "seconds",
+    "temperature_2m": "°F",
+    "relative_humidity_2m": "%",
+    "apparent_temperature": "°F",
+    "is_day": "",
+    "precipitation": "inch",
+    "rain": "inch",
+    "snowfall": "inch",
+    "weather_code": "wmo code",
+    "cloud_cover": "%",
+    "surface_pressure": "hPa",
+    "wind_speed_10m": "mp/h",
+    "wind_direction_10m": "°",
+    "wind_gusts_10m": "mp/h",
+    "uv_index": ""
+  },
+  "current": {
+    "time": "2024-11-04T12:00",
+    "interval": 900,
+    "temperature_2m": 50.0,
+    "relative_humidity_2m": 80,
+    "apparent_temperature": 41.0,
+    "is_day": 0,
+    "precipitation": 0.1,
+    "rain": 0.1,
+    "snowfall": 0.0,
+    "weather_code": 61,
+    "cloud_cover": 90,
+    "surface_pressure": 1012.0,
+    "wind_speed_10m": 10.0,
+    "wind_direction_10m": 90,
+    "wind_gusts_10m": 20.0,
+    "uv_index": 0.0
+  }
+}`
+
+func TestOpenMeteoValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(openMeteoFixture))
+	}))
+	defer server.Close()
+
+	_, err := OpenMeteo{BasePath: server.URL}.Current(context.Background(), 52.52, 13.42)
+
+	t.Run("surfaces problems", func(t *testing.T) {
+		invalid := &fetch.ValidationError{}
+		if !errors.As(err, &invalid) {
+			t.Fatalf("expected a ValidationError, got %v", err)
+		}
+
+		for _, key := range []string{"current.relative_humidity_2m", "current.weather_code", "current.temperature_2m"} {
+			if _, ok := invalid.Problems[key]; !ok {
+				t.Errorf("expected a problem with %s, got %v", key, invalid.Problems)
+			}
+		}
+		if len(invalid.Problems) != 3 {
+			t.Errorf("expected 3 problems, got %v", invalid.Problems)
+		}
+	})
+}
+
+func TestOpenMeteoConditions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(openMeteoImperialFixture))
+	}))
+	defer server.Close()
+
+	conditions, err := OpenMeteo{BasePath: server.URL}.Current(context.Background(), 40.71, -74.01)
+	if err != nil {
+		t.Fatalf("expected conditions, got %v", err)
+	}
+
+	t.Run("converts to stored units", func(t *testing.T) {
+		for name, got := range map[string][2]float64{
+			"temperature":  {conditions.TemperatureC, 10},
+			"apparent":     {*conditions.ApparentTemperatureC, 5},
+			"rain":         {conditions.RainMM, 2.54},
+			"wind speed":   {*conditions.WindSpeedKMH, 16.09344},
+			"wind gusts":   {*conditions.WindGustsKMH, 32.18688},
+			"wind from":    {*conditions.WindDirection, 90},
+			"cloud cover":  {*conditions.CloudCover, 90},
+			"surface hPa":  {*conditions.SurfacePressureHPA, 1012},
+			"precipitated": {*conditions.PrecipitationMM, 2.54},
+		} {
+			if math.Abs(got[0]-got[1]) > 1e-9 {
+				t.Errorf("expected %s of %v, got %v", name, got[1], got[0])
+			}
+		}
+	})
+
+	t.Run("knows whether it is day", func(t *testing.T) {
+		if conditions.IsDay == nil || *conditions.IsDay {
+			t.Errorf("expected night, got %v", conditions.IsDay)
+		}
+	})
+}
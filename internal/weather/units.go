@@ -0,0 +1,349 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Unit is a unit Open-Meteo labels its values with.
+type Unit string
+
+const (
+	UnitCelsius           Unit = "°C"
+	UnitFahrenheit        Unit = "°F"
+	UnitMillimetre        Unit = "mm"
+	UnitCentimetre        Unit = "cm"
+	UnitInch              Unit = "inch"
+	UnitKilometresPerHour Unit = "km/h"
+	UnitMetresPerSecond   Unit = "m/s"
+	UnitMilesPerHour      Unit = "mp/h"
+	UnitKnots             Unit = "kn"
+	UnitPercent           Unit = "%"
+	UnitDegree            Unit = "°"
+	UnitHectopascal       Unit = "hPa"
+)
+
+// Dimension is what a unit measures.
+type Dimension int
+
+const (
+	DimensionTemperature Dimension = iota + 1
+	DimensionLength
+	DimensionSpeed
+	DimensionRatio
+	DimensionAngle
+	DimensionPressure
+)
+
+var unitDimensions = map[Unit]Dimension{
+	UnitCelsius:           DimensionTemperature,
+	UnitFahrenheit:        DimensionTemperature,
+	UnitMillimetre:        DimensionLength,
+	UnitCentimetre:        DimensionLength,
+	UnitInch:              DimensionLength,
+	UnitKilometresPerHour: DimensionSpeed,
+	UnitMetresPerSecond:   DimensionSpeed,
+	UnitMilesPerHour:      DimensionSpeed,
+	UnitKnots:             DimensionSpeed,
+	UnitPercent:           DimensionRatio,
+	UnitDegree:            DimensionAngle,
+	UnitHectopascal:       DimensionPressure,
+}
+
+// ParseUnit parses a unit label from Open-Meteo, expecting it to measure
+// dimension.
+func ParseUnit(s string, dimension Dimension) (Unit, error) {
+	unit := Unit(strings.TrimSpace(s))
+	switch got, ok := unitDimensions[unit]; {
+	case !ok:
+		return "", fmt.Errorf("%q isn't a unit we know", s)
+	case got != dimension:
+		return "", fmt.Errorf("%q doesn't measure %s", s, dimension)
+	default:
+		return unit, nil
+	}
+}
+
+func (d Dimension) String() string {
+	switch d {
+	case DimensionTemperature:
+		return "temperature"
+	case DimensionLength:
+		return "length"
+	case DimensionSpeed:
+		return "speed"
+	case DimensionRatio:
+		return "a ratio"
+	case DimensionAngle:
+		return "an angle"
+	case DimensionPressure:
+		return "pressure"
+	default:
+		return "anything"
+	}
+}
+
+// Temperature is a temperature in degrees Celsius.
+type Temperature float64
+
+// TemperatureIn converts value, measured in unit, to a Temperature.
+func TemperatureIn(value float64, unit Unit) (Temperature, error) {
+	switch unit {
+	case UnitCelsius:
+		return Temperature(value), nil
+	case UnitFahrenheit:
+		return Temperature((value - 32) * 5 / 9), nil
+	default:
+		return 0, fmt.Errorf("%q isn't a temperature unit", unit)
+	}
+}
+
+func (t Temperature) Celsius() float64 {
+	return float64(t)
+}
+
+func (t Temperature) Fahrenheit() float64 {
+	return float64(t)*9/5 + 32
+}
+
+// Depth is a depth of liquid precipitation in millimetres.
+type Depth float64
+
+// DepthIn converts value, measured in unit, to a Depth.
+func DepthIn(value float64, unit Unit) (Depth, error) {
+	switch unit {
+	case UnitMillimetre:
+		return Depth(value), nil
+	case UnitCentimetre:
+		return Depth(value * 10), nil
+	case UnitInch:
+		return Depth(value * mmPerInch), nil
+	default:
+		return 0, fmt.Errorf("%q isn't a length unit", unit)
+	}
+}
+
+func (d Depth) Millimetres() float64 {
+	return float64(d)
+}
+
+func (d Depth) Inches() float64 {
+	return float64(d) / mmPerInch
+}
+
+// Snowfall is a depth of fresh snow in centimetres.
+type Snowfall float64
+
+// SnowfallIn converts value, measured in unit, to a Snowfall.
+func SnowfallIn(value float64, unit Unit) (Snowfall, error) {
+	switch unit {
+	case UnitCentimetre:
+		return Snowfall(value), nil
+	case UnitMillimetre:
+		return Snowfall(value / 10), nil
+	case UnitInch:
+		return Snowfall(value * mmPerInch / 10), nil
+	default:
+		return 0, fmt.Errorf("%q isn't a length unit", unit)
+	}
+}
+
+func (s Snowfall) Centimetres() float64 {
+	return float64(s)
+}
+
+func (s Snowfall) Inches() float64 {
+	return float64(s) * 10 / mmPerInch
+}
+
+// Speed is a wind speed in kilometres per hour.
+type Speed float64
+
+// SpeedIn converts value, measured in unit, to a Speed.
+func SpeedIn(value float64, unit Unit) (Speed, error) {
+	switch unit {
+	case UnitKilometresPerHour:
+		return Speed(value), nil
+	case UnitMetresPerSecond:
+		return Speed(value * 3.6), nil
+	case UnitMilesPerHour:
+		return Speed(value * kmPerMile), nil
+	case UnitKnots:
+		return Speed(value * kmPerNauticalMile), nil
+	default:
+		return 0, fmt.Errorf("%q isn't a speed unit", unit)
+	}
+}
+
+func (s Speed) KilometresPerHour() float64 {
+	return float64(s)
+}
+
+func (s Speed) MilesPerHour() float64 {
+	return float64(s) / kmPerMile
+}
+
+const (
+	mmPerInch         = 25.4
+	kmPerMile         = 1.609344
+	kmPerNauticalMile = 1.852
+	hPaPerInchMercury = 33.8639
+)
+
+// System is a system of units to show values in.
+type System string
+
+const (
+	Metric   System = "metric"
+	Imperial System = "imperial"
+)
+
+// Systems are every system, in the order they are offered.
+var Systems = []System{Metric, Imperial}
+
+func ParseSystem(s string) (System, error) {
+	switch system := System(strings.ToLower(strings.TrimSpace(s))); system {
+	case Metric, Imperial:
+		return system, nil
+	default:
+		return "", fmt.Errorf("%q isn't a unit system", s)
+	}
+}
+
+// Locale is how a visitor writes numbers.
+type Locale struct {
+	Language string
+	Region   string
+}
+
+// decimalCommaLanguages write 1,5 rather than 1.5.
+var decimalCommaLanguages = map[string]bool{
+	"bg": true, "ca": true, "cs": true, "da": true, "de": true, "el": true,
+	"es": true, "et": true, "fi": true, "fr": true, "hr": true, "hu": true,
+	"id": true, "it": true, "lt": true, "lv": true, "nb": true, "nl": true,
+	"nn": true, "no": true, "pl": true, "pt": true, "ro": true, "ru": true,
+	"sk": true, "sl": true, "sr": true, "sv": true, "tr": true, "uk": true,
+	"vi": true,
+}
+
+// imperialRegions are the regions that mostly use imperial units.
+var imperialRegions = map[string]bool{"US": true, "LR": true, "MM": true}
+
+// ParseLocale reads the visitor's preferred locale from an Accept-Language
+// header, ignoring quality values since browsers list languages in order.
+func ParseLocale(acceptLanguage string) Locale {
+	first, _, _ := strings.Cut(acceptLanguage, ",")
+	tag, _, _ := strings.Cut(strings.TrimSpace(first), ";")
+
+	language, region, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if region, _, _ = strings.Cut(region, "-"); len(region) != 2 {
+		region = ""
+	}
+
+	return Locale{Language: strings.ToLower(language), Region: strings.ToUpper(region)}
+}
+
+// DefaultSystem is the system most used where l is from.
+func (l Locale) DefaultSystem() System {
+	if imperialRegions[l.Region] {
+		return Imperial
+	}
+
+	return Metric
+}
+
+// FormatNumber writes value with precision decimals, the way l writes
+// numbers.
+func (l Locale) FormatNumber(value float64, precision int) string {
+	formatted := strconv.FormatFloat(value, 'f', precision, 64)
+	// Rounding can leave -0.0, which reads as a mistake.
+	if strings.Trim(formatted, "-0.") == "" {
+		formatted = strings.TrimPrefix(formatted, "-")
+	}
+
+	if decimalCommaLanguages[l.Language] {
+		formatted = strings.Replace(formatted, ".", ",", 1)
+	}
+
+	return formatted
+}
+
+// Units formats values for a visitor, in their preferred system and the way
+// their locale writes numbers. Values are taken in the units observations
+// are stored in.
+type Units struct {
+	System System
+	Locale Locale
+}
+
+func (u Units) Temperature(c float64) string {
+	if u.System == Imperial {
+		return u.Locale.FormatNumber(Temperature(c).Fahrenheit(), 1) + " °F"
+	}
+
+	return u.Locale.FormatNumber(c, 1) + " °C"
+}
+
+// Precipitation formats a depth of rain, or other liquid precipitation, in
+// millimetres.
+func (u Units) Precipitation(mm float64) string {
+	if u.System == Imperial {
+		return u.Locale.FormatNumber(Depth(mm).Inches(), 2) + " in"
+	}
+
+	return u.Locale.FormatNumber(mm, 1) + " mm"
+}
+
+// Snowfall formats a depth of snow in centimetres.
+func (u Units) Snowfall(cm float64) string {
+	if u.System == Imperial {
+		return u.Locale.FormatNumber(Snowfall(cm).Inches(), 1) + " in"
+	}
+
+	return u.Locale.FormatNumber(cm, 1) + " cm"
+}
+
+// WindSpeed formats a speed in kilometres per hour.
+func (u Units) WindSpeed(kmh float64) string {
+	if u.System == Imperial {
+		return u.Locale.FormatNumber(Speed(kmh).MilesPerHour(), 0) + " mph"
+	}
+
+	return u.Locale.FormatNumber(kmh, 0) + " km/h"
+}
+
+// Pressure formats an air pressure in hectopascals, or inches of mercury as
+// American forecasts give it.
+func (u Units) Pressure(hPa float64) string {
+	if u.System == Imperial {
+		return u.Locale.FormatNumber(hPa/hPaPerInchMercury, 2) + " inHg"
+	}
+
+	return u.Locale.FormatNumber(hPa, 0) + " hPa"
+}
+
+// compassPoints name directions in 45° steps, starting from north.
+var compassPoints = [...]string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+// Direction formats the direction the wind blows from, in degrees clockwise
+// from north, with the nearest compass point.
+func (u Units) Direction(degrees float64) string {
+	point := int(math.Round(math.Mod(degrees, 360)/45)) % len(compassPoints)
+	if point < 0 {
+		point += len(compassPoints)
+	}
+
+	return u.Locale.FormatNumber(degrees, 0) + "° " + compassPoints[point]
+}
+
+// Index formats a unitless index, like the UV index.
+func (u Units) Index(index float64) string {
+	return u.Locale.FormatNumber(index, 1)
+}
+
+// Percent formats a percentage, like relative humidity.
+func (u Units) Percent(percent float64) string {
+	return u.Locale.FormatNumber(percent, 0) + " %"
+}
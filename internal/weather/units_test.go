@@ -0,0 +1,97 @@
+package weather
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnits(t *testing.T) {
+	t.Run("converts between Fahrenheit and Celsius", func(t *testing.T) {
+		for f, c := range map[float64]float64{212: 100, 32: 0, -40: -40, 50: 10} {
+			if got := FToC(f); got != c {
+				t.Errorf("expected %v°F to be %v°C, got %v", f, c, got)
+			}
+			if got := CToF(c); got != f {
+				t.Errorf("expected %v°C to be %v°F, got %v", c, f, got)
+			}
+		}
+	})
+
+	t.Run("converts quantities", func(t *testing.T) {
+		depth, _ := DepthIn(1, UnitInch)
+		snow, _ := SnowfallIn(1, UnitInch)
+		speed, _ := SpeedIn(10, UnitMetresPerSecond)
+		for name, got := range map[string][2]float64{
+			"depth":  {depth.Millimetres(), 25.4},
+			"snow":   {snow.Centimetres(), 2.54},
+			"speed":  {speed.KilometresPerHour(), 36},
+			"inches": {Depth(25.4).Inches(), 1},
+			"mph":    {Speed(kmPerMile).MilesPerHour(), 1},
+		} {
+			if math.Abs(got[0]-got[1]) > 1e-9 {
+				t.Errorf("expected %s of %v, got %v", name, got[1], got[0])
+			}
+		}
+
+		if _, err := TemperatureIn(1, UnitMillimetre); err == nil {
+			t.Errorf("expected millimetres to be rejected as a temperature")
+		}
+	})
+
+	t.Run("parses units by dimension", func(t *testing.T) {
+		if unit, err := ParseUnit("°F", DimensionTemperature); err != nil || unit != UnitFahrenheit {
+			t.Errorf("expected °F, got %v, %v", unit, err)
+		}
+		if _, err := ParseUnit("mm", DimensionTemperature); err == nil {
+			t.Errorf("expected mm to be rejected as a temperature")
+		}
+		if _, err := ParseUnit("furlongs", DimensionLength); err == nil {
+			t.Errorf("expected furlongs to be rejected")
+		}
+	})
+
+	t.Run("reads locales", func(t *testing.T) {
+		for header, expected := range map[string]Locale{
+			"en-US,en;q=0.5": {Language: "en", Region: "US"},
+			"de_de":          {Language: "de", Region: "DE"},
+			"zh-Hant-TW":     {Language: "zh"},
+			"":               {},
+		} {
+			if got := ParseLocale(header); got != expected {
+				t.Errorf("expected %q to be %v, got %v", header, expected, got)
+			}
+		}
+
+		if (Locale{Language: "en", Region: "US"}).DefaultSystem() != Imperial {
+			t.Errorf("expected the US to default to imperial")
+		}
+		if (Locale{Language: "en", Region: "GB"}).DefaultSystem() != Metric {
+			t.Errorf("expected Britain to default to metric")
+		}
+	})
+
+	t.Run("formats values for a locale", func(t *testing.T) {
+		german := Units{System: Metric, Locale: Locale{Language: "de"}}
+		american := Units{System: Imperial, Locale: Locale{Language: "en", Region: "US"}}
+
+		for _, c := range []struct{ got, expected string }{
+			{german.Temperature(1.5), "1,5 °C"},
+			{german.Temperature(-0.01), "0,0 °C"},
+			{american.Temperature(100), "212.0 °F"},
+			{american.Precipitation(25.4), "1.00 in"},
+			{american.Snowfall(2.54), "1.0 in"},
+			{american.WindSpeed(16.09344), "10 mph"},
+			{german.WindSpeed(12.4), "12 km/h"},
+			{german.Percent(81), "81 %"},
+			{german.Pressure(1013.25), "1013 hPa"},
+			{american.Pressure(1013.25), "29.92 inHg"},
+			{german.Direction(225), "225° SW"},
+			{german.Direction(350), "350° N"},
+			{german.Index(3.25), "3,2"},
+		} {
+			if got, expected := c.got, c.expected; got != expected {
+				t.Errorf("expected %q, got %q", expected, got)
+			}
+		}
+	})
+}
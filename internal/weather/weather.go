@@ -1,6 +1,149 @@
 package weather
 
-import "math"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Conditions are the current weather at a location, normalized across
+// providers.
+type Conditions struct {
+	TemperatureC     float64
+	RelativeHumidity float64
+	RainMM           float64
+	SnowfallCM       float64
+	WeatherCode      Code
+	Time             time.Time
+
+	// Not every provider reports these, so they are nil when unknown.
+	ApparentTemperatureC *float64
+	PrecipitationMM      *float64
+	WindSpeedKMH         *float64
+	WindDirection        *float64
+	WindGustsKMH         *float64
+	SurfacePressureHPA   *float64
+	CloudCover           *float64
+	UVIndex              *float64
+	IsDay                *bool
+}
+
+// Provider fetches the current conditions at a location from an upstream
+// weather API.
+type Provider interface {
+	Current(ctx context.Context, lat float64, lon float64) (Conditions, error)
+}
+
+// Chain is an ordered list of providers. Each is tried in turn until one
+// succeeds, so a single upstream outage doesn't leave us without weather.
+type Chain []Provider
+
+func (c Chain) Current(ctx context.Context, lat float64, lon float64) (Conditions, error) {
+	var errs []error
+	for _, provider := range c {
+		conditions, err := provider.Current(ctx, lat, lon)
+		if err == nil {
+			return conditions, nil
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return conditions, ctxErr
+		}
+
+		log.Printf("weather provider %T failed, trying next: %v", provider, err)
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return Conditions{}, errors.New("no weather providers configured")
+	}
+
+	return Conditions{}, errors.Join(errs...)
+}
+
+// ErrNoForecast is returned by Chain.Forecast when none of its providers
+// forecast.
+var ErrNoForecast = errors.New("no weather provider forecasts")
+
+// Forecast tries each provider that also forecasts in turn, like Current.
+// Only Open-Meteo forecasts, so a chain without it fails with
+// ErrNoForecast.
+func (c Chain) Forecast(ctx context.Context, lat float64, lon float64) (Forecast, error) {
+	var errs []error
+	for _, provider := range c {
+		forecaster, ok := provider.(ForecastProvider)
+		if !ok {
+			continue
+		}
+
+		forecast, err := forecaster.Forecast(ctx, lat, lon)
+		if err == nil {
+			return forecast, nil
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return forecast, ctxErr
+		}
+
+		log.Printf("forecast provider %T failed, trying next: %v", provider, err)
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return Forecast{}, ErrNoForecast
+	}
+
+	return Forecast{}, errors.Join(errs...)
+}
+
+// Options configures the providers built by NewProvider.
+type Options struct {
+	OpenMeteoBasePath string
+	METNorwayBasePath string
+
+	// Client makes outbound requests, fetch.DefaultClient when nil.
+	Client *http.Client
+}
+
+// NewProvider returns the provider registered under name.
+func NewProvider(name string, opts Options) (Provider, error) {
+	switch name {
+	case "openmeteo":
+		return OpenMeteo{BasePath: opts.OpenMeteoBasePath, Client: opts.Client}, nil
+	case "metno":
+		return METNorway{BasePath: opts.METNorwayBasePath, Client: opts.Client}, nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider: %q", name)
+	}
+}
+
+// NewChain builds a Chain from provider names, in order.
+func NewChain(names []string, opts Options) (Chain, error) {
+	chain := Chain{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		provider, err := NewProvider(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, provider)
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("no weather providers configured")
+	}
+
+	return chain, nil
+}
 
 const openMeteoPrecision = 2
 
@@ -15,9 +158,10 @@ func toFixed(num float64, precision int) float64 {
 }
 
 func FToC(f float64) float64 {
-	return toFixed(f-32.0*(5.0/9.0), openMeteoPrecision)
+	c, _ := TemperatureIn(f, UnitFahrenheit)
+	return toFixed(c.Celsius(), openMeteoPrecision)
 }
 
 func CToF(c float64) float64 {
-	return toFixed((9.0/5.0)*c+32.0, openMeteoPrecision)
+	return toFixed(Temperature(c).Fahrenheit(), openMeteoPrecision)
 }
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"weather/internal/clientip"
+	"weather/internal/cookie"
+	"weather/internal/data"
+	"weather/internal/gazetteer"
+	"weather/internal/repository"
+	"weather/internal/templates"
+	"weather/internal/validation"
+
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	locationCookieName   = "location"
+	locationCookieMaxAge = 30 * 24 * time.Hour
+)
+
+// locationOverride is a location the visitor chose themselves, which takes
+// precedence over wherever their IP says they are.
+type locationOverride struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+	City      string  `json:"city"`
+	Country   string  `json:"country"`
+	Timezone  string  `json:"tz"`
+}
+
+func (o locationOverride) geolocation() data.Geolocation {
+	return data.Geolocation{
+		Latitude:  o.Latitude,
+		Longitude: o.Longitude,
+		City:      o.City,
+		Country:   o.Country,
+		Timezone:  o.Timezone,
+	}
+}
+
+func hasLocationOverride(values url.Values) bool {
+	return values.Has("lat") || values.Has("lon") || values.Get("city") != ""
+}
+
+// readLocationOverride reads either a city name or a latitude and longitude
+// from values, checking coordinates against the bounds in templateConstants.
+func readLocationOverride(values url.Values) (*locationOverride, error) {
+	v := validation.Validator{}
+
+	if name := strings.TrimSpace(values.Get("city")); name != "" {
+		city, ok := gazetteer.Search(name)
+		if !ok {
+			v.Problem("city", "couldn't find a city called %q", name)
+			return nil, v.Err()
+		}
+
+		return &locationOverride{
+			Latitude:  city.Latitude,
+			Longitude: city.Longitude,
+			City:      city.Name,
+			Country:   city.Country,
+			Timezone:  city.Timezone,
+		}, nil
+	}
+
+	lat := readCoordinate(&v, values, "lat",
+		float64(templateConstants.MinLatitude), float64(templateConstants.MaxLatitude))
+	lon := readCoordinate(&v, values, "lon",
+		float64(templateConstants.MinLongitude), float64(templateConstants.MaxLongitude))
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return &locationOverride{
+		Latitude:  lat,
+		Longitude: lon,
+		Timezone:  approximateTimezone(lon),
+	}, nil
+}
+
+// readCoordinate reads the number in field, recording a problem with v if it
+// is missing, not a number or outside of min and max.
+func readCoordinate(v *validation.Validator, values url.Values, field string, min float64, max float64) float64 {
+	raw := strings.TrimSpace(values.Get(field))
+	if !validation.Field(v, field, raw, validation.Required) {
+		return 0
+	}
+
+	coordinate, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		v.Problem(field, "must be a number")
+		return 0
+	}
+
+	validation.Field(v, field, coordinate, validation.Between(min, max))
+	return coordinate
+}
+
+// approximateTimezone picks the nautical time zone for a longitude, for
+// coordinates we have no real time zone for.
+func approximateTimezone(lon float64) string {
+	offset := int(math.Round(lon / 15))
+	switch {
+	case offset == 0:
+		return "Etc/GMT"
+	case offset > 0:
+		// Etc zones have their signs inverted, Etc/GMT-5 is UTC+5.
+		return fmt.Sprintf("Etc/GMT-%d", offset)
+	default:
+		return fmt.Sprintf("Etc/GMT+%d", -offset)
+	}
+}
+
+// resolveVisitorLocation finds where the visitor wants weather for: a
+// location in the query string, then one they chose earlier, then wherever
+// their IP is. The bool reports whether the location was chosen by them.
+//
+// A location in the query string only applies to that request. It is only
+// remembered when posted to /location, so a link can't quietly move someone.
+func resolveVisitorLocation(
+	ctx context.Context,
+	r *http.Request,
+	signer *cookie.Signer,
+	clientIPs *clientip.Resolver,
+	geolocations repository.GeolocationRepository,
+) (data.Geolocation, bool, error) {
+	if query := r.URL.Query(); hasLocationOverride(query) {
+		override, err := readLocationOverride(query)
+		if err != nil {
+			return data.Geolocation{}, false, err
+		}
+
+		return override.geolocation(), true, nil
+	}
+
+	override := locationOverride{}
+	switch err := signer.Get(r, locationCookieName, &override); {
+	case err == nil:
+		return override.geolocation(), true, nil
+	case errors.Is(err, cookie.ErrInvalid):
+		break
+	default:
+		return data.Geolocation{}, false, err
+	}
+
+	addr, err := clientIPs.ClientIP(r)
+	if err != nil {
+		return data.Geolocation{}, false, err
+	}
+
+	loc, err := geolocations.ForIP(ctx, addr.String())
+	return loc, false, err
+}
+
+// redirectHome sends the visitor back to the index once their location is
+// stored. htmx would follow a plain redirect and swap the whole page into
+// the form, so it is asked to navigate instead.
+func redirectHome(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func handleLocationPost(tmpl *templates.TemplateEngine, signer *cookie.Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "uh oh, I couldn't read that location :(", http.StatusBadRequest)
+			return
+		}
+
+		if r.PostForm.Has("clear") {
+			signer.Clear(w, locationCookieName)
+			redirectHome(w, r)
+			return
+		}
+
+		override, err := readLocationOverride(r.PostForm)
+		if err != nil {
+			validation.WriteError(w, r, err, "location", tmpl, "find .validation-problems")
+			return
+		}
+
+		if err := signer.Set(w, locationCookieName, override, locationCookieMaxAge); err != nil {
+			log.Printf("error storing location override: %v", err)
+			http.Error(w, "uh oh, I beefed it :(", http.StatusInternalServerError)
+			return
+		}
+
+		redirectHome(w, r)
+	})
+}
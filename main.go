@@ -1,147 +1,208 @@
 package main
 
 import (
+	"weather/internal/clientip"
+	"weather/internal/config"
+	"weather/internal/cookie"
 	"weather/internal/data"
 	"weather/internal/drawing"
+	"weather/internal/fetch"
+	"weather/internal/gazetteer"
 	"weather/internal/location"
-	"weather/internal/observation"
+	"weather/internal/repository"
 	"weather/internal/templates"
 	"weather/internal/validation"
 	"weather/internal/weather"
 
 	"context"
-	"database/sql"
+	"crypto/rand"
 	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
-func resolveGeolocation(ctx context.Context, ip string, db *data.Queries) (data.Geolocation, error) {
-	entry, err := db.GetGeolocation(ctx, ip)
-	switch err {
-	case nil:
-		return entry, nil
-	case sql.ErrNoRows:
-		log.Printf("fetching location for %v", ip)
-
-		loc, err := location.ForIP(ip)
-		if err != nil {
-			return entry, err
-		}
-
-		entry, err = db.AddGeolocation(ctx, data.AddGeolocationParams{
-			Ip:        ip,
-			Latitude:  loc.Lat,
-			Longitude: loc.Lon,
-			City:      loc.City,
-			Country:   loc.Country,
-			Timezone:  loc.Timezone,
-		})
-		if err != nil {
-			return entry, nil
-		}
-
-		return entry, nil
-	default:
-		return entry, err
-	}
+type observationTemplateData struct {
+	Observation data.Observation         `json:"observation"`
+	Drawing     *data.ObservationDrawing `json:"drawing,omitempty"`
+	Units       weather.Units            `json:"-"`
 }
 
-func resolveObservation(ctx context.Context, loc data.Geolocation, db *data.Queries) (*data.Observation, error) {
-	wth, err := weather.ForLatLon(loc.Latitude, loc.Longitude)
+func resolveObservationByID(
+	ctx context.Context,
+	id int64,
+	observations repository.ObservationRepository,
+	drawings repository.DrawingRepository,
+) (*observationTemplateData, error) {
+	obs, err := observations.ByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	tzloc, err := time.LoadLocation(loc.Timezone)
-	if err != nil {
-		tzloc = time.UTC
-	}
-
-	obs, err := db.AddObservation(ctx, data.AddObservationParams{
-		Latitude:         loc.Latitude,
-		Longitude:        loc.Longitude,
-		Timezone:         loc.Timezone,
-		TempC:            wth.Current.Temperature2m,
-		TempF:            weather.CToF(wth.Current.Temperature2m),
-		Rain:             wth.Current.Rain,
-		Snowfall:         wth.Current.Snowfall,
-		WeatherCode:      strconv.Itoa(wth.Current.WeatherCode),
-		RelativeHumidity: float64(wth.Current.RelativeHumidity2m),
-		TimeUtc:          time.Now().UTC(),
-		TimeLocal:        time.Now().In(tzloc),
-	})
-
-	if err != nil {
+	drawing, err := drawings.ForObservation(ctx, id)
+	switch {
+	case err == nil:
+		return &observationTemplateData{Observation: obs, Drawing: &drawing}, nil
+	case errors.Is(err, repository.ErrNotFound):
+		return &observationTemplateData{Observation: obs}, nil
+	default:
 		return nil, err
 	}
-
-	return &obs, nil
 }
 
-func resolveObservationByID(ctx context.Context, id int64) (*data.Observation, error) {
-	// TODO
-	return nil, nil
-}
+var observationIDPattern = regexp.MustCompile(`^[1-9][0-9]{0,17}$`)
+
+func readObservationDrawing(r *http.Request, limits drawing.Limits) (*data.ObservationDrawing, error) {
+	v := validation.Validator{}
 
-func readObservationDrawing(r *http.Request) (*data.ObservationDrawing, error) {
-	drawingData := r.PostFormValue("drawing")
 	idStr := r.PathValue("id")
-	if idStr == "" {
-		return nil, validation.ErrValidation
-	}
+	validation.Field(&v, "id", idStr, validation.Matches(observationIDPattern, "a positive whole number"))
 
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		return nil, validation.ErrValidation
+	drawingData := r.PostFormValue("drawing")
+	if validation.Field(&v, "drawing", drawingData, validation.Required) {
+		if _, err := limits.Parse(drawingData); err != nil {
+			v.Merge(err, "drawing")
+		}
 	}
 
-	if err := drawing.Validate(drawingData); err != nil {
-		return nil, validation.ErrValidation
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
 
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+
 	return &data.ObservationDrawing{
-		ObservationID: int64(id),
+		ObservationID: id,
 		Data:          drawingData,
 		SizeBytes:     int64(len([]byte(drawingData))),
 		TimeSubmitted: time.Now().UTC(),
 	}, nil
 }
 
-func createObservationDrawing(ctx context.Context, drawing *data.ObservationDrawing, db *data.Queries) error {
-	return db.AddObservationDrawing(ctx, data.AddObservationDrawingParams{
-		ObservationID: drawing.ObservationID,
-		Data:          drawing.Data,
-		SizeBytes:     drawing.SizeBytes,
-		TimeSubmitted: drawing.TimeSubmitted,
+// writeDrawingError responds to a drawing that couldn't be stored, showing
+// htmx requests why in the form like a validation problem.
+func writeDrawingError(w http.ResponseWriter, r *http.Request, err error, tmpl *templates.TemplateEngine, target string) {
+	var status int
+	var message string
+	switch {
+	case errors.Is(err, data.ErrNotFound):
+		status, message = http.StatusNotFound, "that observation doesn't exist"
+	case errors.Is(err, data.ErrConflict):
+		status, message = http.StatusConflict, "someone nearby already drew this weather, reload to see it"
+	case errors.Is(err, data.ErrConstraint):
+		status, message = http.StatusBadRequest, "that drawing can't be stored"
+	case errors.Is(err, context.Canceled):
+		return
+	default:
+		log.Printf("error storing drawing: %v", err)
+		status, message = http.StatusInternalServerError, "uh oh, I beefed it :("
+	}
+
+	validation.WriteErrorStatus(w, r, status, validation.Problem("drawing", "%s", message), "drawing", tmpl, target)
+}
+
+// isUpstreamInvalid reports whether err comes from an upstream API
+// responding with something that failed validation, which is the upstream's
+// fault rather than the visitor's.
+func isUpstreamInvalid(err error) bool {
+	invalid := &fetch.ValidationError{}
+	return errors.As(err, &invalid)
+}
+
+// requireSameOrigin refuses requests a browser says came from another site,
+// so a page elsewhere can't post a form that changes a visitor's cookies.
+// Sec-Fetch-Site is trusted when it's sent, else Origin is checked against
+// the host. Requests with neither didn't come from a browser that would
+// send the visitor's cookies along.
+func requireSameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isSameOrigin(r) {
+			http.Error(w, "uh oh, that came from another site :(", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
 
-func handleIndexGet(tmpl *templates.TemplateEngine, db *data.Queries) http.Handler {
+func isSameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+		break
+	default:
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return u.Host == r.Host
+}
+
+func handleIndexGet(
+	tmpl *templates.TemplateEngine,
+	signer *cookie.Signer,
+	clientIPs *clientip.Resolver,
+	geolocations repository.GeolocationRepository,
+	observations repository.ObservationRepository,
+	drawings repository.DrawingRepository,
+	forecasts repository.ForecastRepository,
+) http.Handler {
 	const indexTemplateName = "templates/index.template.html"
 
+	// forecastHours is how far ahead the index shows the forecast.
+	const forecastHours = 24
+
+	type forecastTemplateData struct {
+		Hours []weather.HourlyForecast
+		Today *weather.DailyForecast
+		Units weather.Units
+	}
+
 	type indexTemplateData struct {
-		Location        data.Geolocation
-		PrevObservation data.Observation
-		NextObservation data.Observation
+		Location         data.Geolocation
+		LocationOverride bool
+		PrevObservation  *observationTemplateData
+		NextObservation  observationTemplateData
+		Forecast         *forecastTemplateData
+		Units            weather.Units
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		requestAddress := r.RemoteAddr
-		ip := strings.Split(requestAddress, ":")[0]
-
-		loc, err := resolveGeolocation(ctx, ip, db)
+		loc, overridden, err := resolveVisitorLocation(ctx, r, signer, clientIPs, geolocations)
 		if err != nil {
-			switch err {
-			case context.Canceled:
+			switch {
+			case errors.Is(err, validation.ErrValidation):
+				validation.WriteError(w, r, err, "location", nil, "")
+				return
+			case isUpstreamInvalid(err):
+				log.Printf("error resolving geolocation: %v", err)
+				http.Error(w, "uh oh, I got a weird location back :(", http.StatusBadGateway)
+				return
+			case errors.Is(err, context.Canceled):
 			default:
 				log.Printf("error resolving geolocation: %v", err)
 				break
@@ -151,10 +212,14 @@ func handleIndexGet(tmpl *templates.TemplateEngine, db *data.Queries) http.Handl
 			return
 		}
 
-		obs, err := resolveObservation(ctx, loc, db)
+		obs, err := observations.ForLocation(ctx, loc)
 		if err != nil {
-			switch err {
-			case context.Canceled:
+			switch {
+			case isUpstreamInvalid(err):
+				log.Printf("error resolving observation: %v", err)
+				http.Error(w, "uh oh, I got weird weather back :(", http.StatusBadGateway)
+				return
+			case errors.Is(err, context.Canceled):
 			default:
 				log.Printf("error resolving observation: %v", err)
 				break
@@ -164,7 +229,24 @@ func handleIndexGet(tmpl *templates.TemplateEngine, db *data.Queries) http.Handl
 			return
 		}
 
-		prev, err := observation.ResolvePriorObservation(ctx, *obs, db)
+		// Everyone in a cell shares its observation, so someone nearby may
+		// have drawn it already. Show theirs rather than a form that can
+		// only be refused.
+		var drawn *data.ObservationDrawing
+		if stored, err := drawings.ForObservation(ctx, obs.ID); err == nil {
+			drawn = &stored
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			switch {
+			case errors.Is(err, context.Canceled):
+			default:
+				log.Printf("error resolving drawing: %v", err)
+			}
+
+			http.Error(w, "uh oh, I beefed it :(", http.StatusInternalServerError)
+			return
+		}
+
+		prev, err := observations.Prior(ctx, obs)
 		if err != nil {
 			switch err {
 			case context.Canceled:
@@ -177,73 +259,168 @@ func handleIndexGet(tmpl *templates.TemplateEngine, db *data.Queries) http.Handl
 			return
 		}
 
-		if err := tmpl.Render(w, indexTemplateName, indexTemplateData{
-			Location:        loc,
-			PrevObservation: *prev,
-			NextObservation: *obs,
-		}); err != nil {
+		units := resolveUnits(r, signer)
+		templateData := indexTemplateData{
+			Location:         loc,
+			LocationOverride: overridden,
+			NextObservation:  observationTemplateData{Observation: obs, Drawing: drawn, Units: units},
+			Units:            units,
+		}
+		if prev != nil {
+			templateData.PrevObservation = &observationTemplateData{
+				Observation: prev.Observation,
+				Drawing:     &prev.Drawing,
+				Units:       units,
+			}
+		}
+
+		// The forecast is extra, so the page is still worth showing without
+		// it.
+		forecast, err := forecasts.ForLocation(ctx, loc)
+		switch {
+		case err == nil:
+			now := time.Now()
+			templateData.Forecast = &forecastTemplateData{
+				Hours: forecast.HoursFrom(now, forecastHours),
+				Units: units,
+			}
+			if today, ok := forecast.Day(now); ok {
+				templateData.Forecast.Today = &today
+			}
+		case errors.Is(err, context.Canceled):
+			return
+		case errors.Is(err, weather.ErrNoForecast):
+			break
+		default:
+			log.Printf("error resolving forecast: %v", err)
+		}
+
+		if err := tmpl.Render(w, indexTemplateName, templateData); err != nil {
 			log.Printf("error rendering index template: %v", err)
 			return
 		}
 	})
 }
 
-func handleObservationDrawingPost(tmpl *templates.TemplateEngine, db *data.Queries) http.Handler {
-	const observationTemplateName = "templates/fragments/observation.template.html"
-
-	type observationTemplateData struct {
-		Observation data.Observation
-		Drawing     data.ObservationDrawing
-	}
+func handleObservationGet(
+	tmpl *templates.TemplateEngine,
+	signer *cookie.Signer,
+	observations repository.ObservationRepository,
+	drawings repository.DrawingRepository,
+) http.Handler {
+	const observationTemplateName = "templates/observation.template.html"
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		drawing, err := readObservationDrawing(r)
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 		if err != nil {
-			http.Error(w, "", http.StatusBadRequest)
+			http.Error(w, "uh oh, I couldn't find that observation :(", http.StatusNotFound)
 			return
 		}
 
-		observation, err := db.GetObservation(ctx, drawing.ObservationID)
-		switch err {
-		case nil:
-			break
-		case sql.ErrNoRows: // TODO: figure out what kind of error is returned on foreign key constraint failed
-			http.Error(w, "", http.StatusBadRequest)
-			return
-		default:
-			http.Error(w, "", http.StatusInternalServerError)
+		obs, err := resolveObservationByID(ctx, id, observations, drawings)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrNotFound):
+				http.Error(w, "uh oh, I couldn't find that observation :(", http.StatusNotFound)
+			case errors.Is(err, context.Canceled):
+			default:
+				log.Printf("error resolving observation %v: %v", id, err)
+				http.Error(w, "uh oh, I beefed it :(", http.StatusInternalServerError)
+			}
 			return
 		}
 
-		if err := createObservationDrawing(ctx, drawing, db); err != nil {
-			http.Error(w, "", http.StatusInternalServerError)
+		if validation.PrefersJSON(r) {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(obs); err != nil {
+				log.Printf("error encoding observation %v: %v", id, err)
+			}
+			return
 		}
 
-		tmpl.Render(w, observationTemplateName, observationTemplateData{
-			Observation: observation,
-			Drawing:     *drawing,
-		})
+		obs.Units = resolveUnits(r, signer)
+		if err := tmpl.Render(w, observationTemplateName, obs); err != nil {
+			log.Printf("error rendering observation template: %v", err)
+			return
+		}
 	})
 }
 
-func createDatabase(path string, ddl string) (*data.Queries, error) {
-	db, err := sql.Open("sqlite3", path)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't open database connection: %w", err)
-	}
+// handleObservationDrawingPNGGet renders a drawing with the current palette.
+// Pages link to it with the palette's hash, so browsers can cache it for as
+// long as that URL is current and fetch it again once the palette changes.
+func handleObservationDrawingPNGGet(drawings repository.DrawingRepository, palette string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 
-	_, err = db.Exec(ddl)
-	if err != nil {
-		return nil, fmt.Errorf("could apply database schema: %w", err)
-	}
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "uh oh, I couldn't find that drawing :(", http.StatusNotFound)
+			return
+		}
 
-	return data.New(db), nil
+		png, err := drawings.PNG(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrNotFound):
+				http.Error(w, "uh oh, I couldn't find that drawing :(", http.StatusNotFound)
+			case errors.Is(err, context.Canceled):
+			default:
+				log.Printf("error resolving drawing PNG: %v", err)
+				http.Error(w, "uh oh, I beefed it :(", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		if r.URL.Query().Get("palette") == palette {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		w.Write(png)
+	})
 }
 
-//go:embed sqlite/schema.sql
-var schema string
+func handleObservationDrawingPost(
+	tmpl *templates.TemplateEngine,
+	signer *cookie.Signer,
+	observations repository.ObservationRepository,
+	drawings repository.DrawingRepository,
+	limits drawing.Limits,
+) http.Handler {
+	const observationFragmentName = "observation"
+	const problemsTarget = "find .validation-problems"
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		drawing, err := readObservationDrawing(r, limits)
+		if err != nil {
+			validation.WriteError(w, r, err, "drawing", tmpl, problemsTarget)
+			return
+		}
+
+		observation, err := observations.ByID(ctx, drawing.ObservationID)
+		if err != nil {
+			writeDrawingError(w, r, err, tmpl, problemsTarget)
+			return
+		}
+
+		if err := drawings.Add(ctx, *drawing); err != nil {
+			writeDrawingError(w, r, err, tmpl, problemsTarget)
+			return
+		}
+
+		tmpl.RenderFragment(w, observationFragmentName, observationTemplateData{
+			Observation: observation,
+			Drawing:     drawing,
+			Units:       resolveUnits(r, signer),
+		})
+	})
+}
 
 //go:embed templates/*
 var templateFS embed.FS
@@ -256,14 +433,117 @@ var templateConstants = struct {
 	MaxLatitude  float32
 	MinLongitude float32
 	MaxLongitude float32
+	CanvasWidth  uint16
+	CanvasHeight uint16
+	Colorset     string
+	Brushset     string
+	PaletteHash  string
+	Cities       []string
+	UnitSystems  []weather.System
 }{
 	MinLatitude:  -90.0,
 	MaxLatitude:  90.0,
 	MinLongitude: -180.0,
 	MaxLongitude: 180.0,
+	Cities:       gazetteer.Names(),
+	UnitSystems:  weather.Systems,
+}
+
+func newCookieSigner(secret string) (*cookie.Signer, error) {
+	if secret != "" {
+		return cookie.NewSigner([]byte(secret)), nil
+	}
+
+	log.Printf("no cookie secret configured, chosen locations won't survive a restart")
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("error generating cookie secret: %w", err)
+	}
+
+	return cookie.NewSigner(key), nil
+}
+
+func joinInts(ints []int, sep string) string {
+	strs := make([]string, len(ints))
+	for i, n := range ints {
+		strs[i] = strconv.Itoa(n)
+	}
+
+	return strings.Join(strs, sep)
 }
 
 func main() {
+	inv, err := config.Load(os.Args[0], os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("error loading configuration: %v", err)
+	}
+	cfg := inv.Config
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	if inv.PrintConfig {
+		if err := cfg.WriteYAML(os.Stdout); err != nil {
+			log.Fatalf("error printing configuration: %v", err)
+		}
+		return
+	}
+
+	if len(inv.Args) > 0 && inv.Args[0] == "migrate" {
+		if err := runMigrateCommand(context.Background(), cfg.DBPath, inv.Args[1:]); err != nil {
+			log.Fatalf("error running migrations: %v", err)
+		}
+		return
+	}
+
+	clientIPs, err := clientip.NewResolver(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("error configuring trusted proxies: %v", err)
+	}
+
+	signer, err := newCookieSigner(cfg.CookieSecret)
+	if err != nil {
+		log.Fatalf("error configuring cookie signing: %v", err)
+	}
+
+	breakers := fetch.NewBreakers(cfg.Upstream.BreakerPolicy())
+	upstream := fetch.NewClient(fetch.Options{
+		Timeout:      time.Duration(cfg.Upstream.Timeout),
+		HostTimeouts: cfg.Upstream.Timeouts(),
+		Retry:        cfg.Upstream.RetryPolicy(),
+		Breakers:     breakers,
+	})
+
+	locationChain, err := location.NewChain(cfg.Location.ProviderNames(), location.Options{
+		IPAPIBasePath: cfg.Location.IPAPIBasePath,
+		MMDBPath:      cfg.Location.MMDBPath,
+		Client:        upstream,
+	})
+	if err != nil {
+		log.Fatalf("error configuring location providers: %v", err)
+	}
+
+	weatherChain, err := weather.NewChain(cfg.Weather.Providers, weather.Options{
+		OpenMeteoBasePath: cfg.Weather.OpenMeteoBasePath,
+		METNorwayBasePath: cfg.Weather.METNorwayBasePath,
+		Client:            upstream,
+	})
+	if err != nil {
+		log.Fatalf("error configuring weather providers: %v", err)
+	}
+
+	limits := cfg.Drawing.Limits()
+	templateConstants.CanvasWidth = limits.Width
+	templateConstants.CanvasHeight = limits.Height
+	templateConstants.Colorset = strings.Join(limits.Palette.Colors, ",")
+	templateConstants.Brushset = joinInts(limits.Palette.BrushSizes, ",")
+	templateConstants.PaletteHash = limits.Palette.Hash()
+
 	templates, err := templates.Init(
 		templateFS,
 		templateConstants,
@@ -275,10 +555,11 @@ func main() {
 		log.Fatalf("error parsing templates: %v", err)
 	}
 
-	db, err := createDatabase("./db.sqlite", schema)
+	sqlDB, err := createDatabase(cfg.DBPath)
 	if err != nil {
 		log.Fatalf("error creating database: %v", err)
 	}
+	db := data.New(sqlDB)
 
 	server := http.NewServeMux()
 
@@ -287,15 +568,183 @@ func main() {
 		http.FileServerFS(staticFS),
 	)
 
+	geolocations := repository.NewGeolocationRepository(db, locationChain, time.Duration(cfg.Location.TTL))
+	observations := repository.NewObservationRepository(db, weatherChain, time.Duration(cfg.Weather.TTL))
+	drawings := repository.NewDrawingRepository(db, limits.Palette)
+	forecasts := repository.NewForecastRepository(sqlDB, weatherChain, time.Duration(cfg.Weather.ForecastTTL))
+
+	server.Handle(
+		"GET /{$}",
+		handleIndexGet(templates, signer, clientIPs, geolocations, observations, drawings, forecasts),
+	)
+
 	server.Handle(
-		"GET /",
-		handleIndexGet(templates, db),
+		"POST /location",
+		requireSameOrigin(handleLocationPost(templates, signer)),
+	)
+
+	server.Handle(
+		"POST /units",
+		requireSameOrigin(handleUnitsPost(templates, signer)),
+	)
+
+	server.Handle(
+		"GET /observations",
+		handleObservationsGet(templates, signer, observations, drawings),
+	)
+
+	server.Handle(
+		"GET /observations/{id}",
+		handleObservationGet(templates, signer, observations, drawings),
+	)
+
+	server.Handle(
+		"GET /observations/{id}/drawing.png",
+		handleObservationDrawingPNGGet(drawings, templateConstants.PaletteHash),
 	)
 
 	server.Handle(
 		"POST /observations/{id}/drawings",
-		handleObservationDrawingPost(templates, db),
+		handleObservationDrawingPost(templates, signer, observations, drawings, limits),
 	)
 
-	http.ListenAndServe("localhost:8080", server)
+	server.Handle(
+		"GET /api/v1/openapi.yaml",
+		handleAPIOpenAPIGet(),
+	)
+
+	server.Handle(
+		"GET /api/v1/observations",
+		handleAPIObservationsGet(observations),
+	)
+
+	server.Handle(
+		"GET /api/v1/observations/current",
+		handleAPICurrentObservationGet(observations),
+	)
+
+	server.Handle(
+		"GET /api/v1/observations/{id}",
+		handleAPIObservationGet(observations, drawings),
+	)
+
+	server.Handle(
+		"GET /api/v1/observations/{id}/drawing",
+		handleAPIObservationDrawingGet(drawings),
+	)
+
+	server.Handle(
+		"POST /api/v1/observations/{id}/drawing",
+		handleAPIObservationDrawingPost(drawings, limits),
+	)
+
+	server.Handle(
+		"GET /api/v1/geolocation",
+		handleAPIGeolocationGet(clientIPs, geolocations),
+	)
+
+	server.Handle(
+		"/api/",
+		handleAPINotFound(),
+	)
+
+	httpServer := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           server,
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		sqlDB.Close()
+		log.Fatalf("couldn't listen on %s: %v", cfg.Addr, err)
+	}
+	log.Printf("listening on %s", listener.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Metrics are served on their own listener, which can stay private while
+	// addr is public.
+	metricsDone := make(chan error, 1)
+	if cfg.MetricsAddr == "" {
+		metricsDone <- nil
+	} else {
+		metrics := http.NewServeMux()
+		metrics.Handle(
+			"GET /metrics",
+			handleMetricsGet(breakers),
+		)
+
+		metricsListener, err := net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			listener.Close()
+			sqlDB.Close()
+			log.Fatalf("couldn't listen on %s: %v", cfg.MetricsAddr, err)
+		}
+		log.Printf("serving metrics on %s", metricsListener.Addr())
+
+		metricsServer := &http.Server{
+			Handler:           metrics,
+			ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+			WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+		}
+		go func() {
+			metricsDone <- serve(ctx, metricsServer, metricsListener, time.Duration(cfg.Server.ShutdownTimeout))
+		}()
+	}
+
+	exitCode := 0
+	if err := serve(ctx, httpServer, listener, time.Duration(cfg.Server.ShutdownTimeout)); err != nil {
+		log.Printf("error serving: %v", err)
+		exitCode = 1
+	}
+
+	stop()
+	if err := <-metricsDone; err != nil {
+		log.Printf("error serving metrics: %v", err)
+	}
+
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("error closing database: %v", err)
+		exitCode = 1
+	}
+
+	if err := locationChain.Close(); err != nil {
+		log.Printf("error closing location providers: %v", err)
+	}
+
+	os.Exit(exitCode)
+}
+
+// serve runs srv until ctx is done, then stops accepting connections and
+// waits up to timeout for in-flight requests, such as pending drawing
+// inserts, to finish.
+func serve(ctx context.Context, srv *http.Server, listener net.Listener, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %v for in-flight requests", timeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("error draining in-flight requests: %w", err)
+	}
+
+	return nil
 }
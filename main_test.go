@@ -0,0 +1,340 @@
+package main
+
+import (
+	"weather/internal/clientip"
+	"weather/internal/cookie"
+	"weather/internal/data"
+	"weather/internal/drawing"
+	"weather/internal/fetch"
+	"weather/internal/observation"
+	"weather/internal/repository"
+	"weather/internal/templates"
+	"weather/internal/weather"
+
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDrawing is a valid drawing for the default limits.
+const testDrawing = "AQH0AfQAAQACAAEAAgADAAQ="
+
+func newTestTemplates(t *testing.T) *templates.TemplateEngine {
+	t.Helper()
+
+	tmpl, err := templates.Init(
+		templateFS,
+		templateConstants,
+		"templates/root.template.html",
+		"templates/common/*.template.html",
+		"templates/fragments/*.template.html",
+	)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return tmpl
+}
+
+// fakeObservations is an ObservationRepository over a map, for handlers
+// that only read observations by ID or share one observation.
+type fakeObservations struct {
+	repository.ObservationRepository
+	byID map[int64]data.Observation
+}
+
+func (f *fakeObservations) ByID(ctx context.Context, id int64) (data.Observation, error) {
+	obs, ok := f.byID[id]
+	if !ok {
+		return obs, repository.ErrNotFound
+	}
+	return obs, nil
+}
+
+// ForLocation shares observation 1 with everyone.
+func (f *fakeObservations) ForLocation(ctx context.Context, loc data.Geolocation) (data.Observation, error) {
+	return f.ByID(ctx, 1)
+}
+
+func (f *fakeObservations) Prior(ctx context.Context, obs data.Observation) (*observation.PriorObservation, error) {
+	return nil, nil
+}
+
+// fakeDrawings is a DrawingRepository over a map, with one drawing per
+// observation like the real one.
+type fakeDrawings struct {
+	repository.DrawingRepository
+	byObservation map[int64]data.ObservationDrawing
+}
+
+func (f *fakeDrawings) ForObservation(ctx context.Context, observationID int64) (data.ObservationDrawing, error) {
+	stored, ok := f.byObservation[observationID]
+	if !ok {
+		return stored, repository.ErrNotFound
+	}
+	return stored, nil
+}
+
+func (f *fakeDrawings) Add(ctx context.Context, stored data.ObservationDrawing) error {
+	if _, ok := f.byObservation[stored.ObservationID]; ok {
+		return data.ErrConflict
+	}
+	f.byObservation[stored.ObservationID] = stored
+	return nil
+}
+
+func TestHandleObservationGet(t *testing.T) {
+	observations := &fakeObservations{byID: map[int64]data.Observation{
+		1: {ID: 1, TempC: 12.5, Timezone: "UTC"},
+		2: {ID: 2, TempC: 3, Timezone: "UTC"},
+	}}
+	drawings := &fakeDrawings{byObservation: map[int64]data.ObservationDrawing{
+		1: {ObservationID: 1, Data: testDrawing},
+	}}
+
+	handler := handleObservationGet(newTestTemplates(t), cookie.NewSigner([]byte("test")), observations, drawings)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.SetPathValue("id", strings.TrimPrefix(path, "/observations/"))
+		r.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("includes the drawing", func(t *testing.T) {
+		w := get("/observations/1")
+
+		body := observationTemplateData{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %q (%v)", w.Code, w.Body.String(), err)
+		}
+		if body.Observation.TempC != 12.5 || body.Drawing == nil || body.Drawing.Data != testDrawing {
+			t.Errorf("expected observation 1 with its drawing, got %+v", body)
+		}
+	})
+
+	t.Run("leaves out a missing drawing", func(t *testing.T) {
+		w := get("/observations/2")
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"drawing"`) {
+			t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("can't find missing observations", func(t *testing.T) {
+		if w := get("/observations/3"); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleObservationDrawingPost(t *testing.T) {
+	observations := &fakeObservations{byID: map[int64]data.Observation{
+		1: {ID: 1, Timezone: "UTC"},
+	}}
+	drawings := &fakeDrawings{byObservation: map[int64]data.ObservationDrawing{}}
+
+	handler := handleObservationDrawingPost(newTestTemplates(t), cookie.NewSigner([]byte("test")), observations, drawings, drawing.DefaultLimits)
+
+	post := func(id string) *httptest.ResponseRecorder {
+		form := url.Values{"drawing": {testDrawing}}
+		r := httptest.NewRequest(http.MethodPost, "/observations/"+id+"/drawings", strings.NewReader(form.Encode()))
+		r.SetPathValue("id", id)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("stores the drawing", func(t *testing.T) {
+		w := post("1")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/observations/1/drawing.png") {
+			t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+		}
+		if _, ok := drawings.byObservation[1]; !ok {
+			t.Errorf("expected the drawing to be stored")
+		}
+	})
+
+	t.Run("refuses a second drawing", func(t *testing.T) {
+		w := post("1")
+		if w.Code != http.StatusConflict || w.Header().Get("HX-Retarget") == "" {
+			t.Errorf("unexpected response %d %v", w.Code, w.Header())
+		}
+	})
+
+	t.Run("can't find missing observations", func(t *testing.T) {
+		if w := post("2"); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+}
+
+// steadyWeather reports the same conditions everywhere.
+type steadyWeather struct{}
+
+func (steadyWeather) Current(ctx context.Context, lat float64, lon float64) (weather.Conditions, error) {
+	return weather.Conditions{TemperatureC: 10, WeatherCode: 3, Time: time.Now()}, nil
+}
+
+// noForecasts has no forecast for anywhere, which the index shows without.
+type noForecasts struct{}
+
+func (noForecasts) ForLocation(ctx context.Context, loc data.Geolocation) (weather.Forecast, error) {
+	return weather.Forecast{}, repository.ErrNotFound
+}
+
+func TestSharedObservationDrawing(t *testing.T) {
+	sqlDB, err := createDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	clientIPs, err := clientip.NewResolver(nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	db := data.New(sqlDB)
+	tmpl := newTestTemplates(t)
+	signer := cookie.NewSigner([]byte("test"))
+	observations := repository.NewObservationRepository(db, steadyWeather{}, time.Hour)
+	drawings := repository.NewDrawingRepository(db, drawing.DefaultLimits.Palette)
+
+	index := handleIndexGet(tmpl, signer, clientIPs, nil, observations, drawings, noForecasts{})
+	post := handleObservationDrawingPost(tmpl, signer, observations, drawings, drawing.DefaultLimits)
+
+	// Two visitors a few hundred meters apart share a cell.
+	visit := func(lat string, lon string) string {
+		r := httptest.NewRequest(http.MethodGet, "/?lat="+lat+"&lon="+lon, nil)
+		w := httptest.NewRecorder()
+		index.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+	draw := func() *httptest.ResponseRecorder {
+		form := url.Values{"drawing": {testDrawing}}
+		r := httptest.NewRequest(http.MethodPost, "/observations/1/drawings", strings.NewReader(form.Encode()))
+		r.SetPathValue("id", "1")
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		post.ServeHTTP(w, r)
+		return w
+	}
+
+	const form = `hx-post="/observations/1/drawings"`
+
+	t.Run("both visitors can draw until one does", func(t *testing.T) {
+		for _, body := range []string{visit("52.521", "13.419"), visit("52.519", "13.421")} {
+			if !strings.Contains(body, form) {
+				t.Errorf("expected a form for observation 1, got %q", body)
+			}
+		}
+
+		if w := draw(); w.Code != http.StatusOK {
+			t.Fatalf("expected the first drawing to be stored, got %d %q", w.Code, w.Body.String())
+		}
+		if w := draw(); w.Code != http.StatusConflict {
+			t.Errorf("expected the second drawing to conflict, got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("later visitors see the drawing instead of a form", func(t *testing.T) {
+		body := visit("52.519", "13.421")
+		if strings.Contains(body, form) || !strings.Contains(body, "/observations/1/drawing.png") {
+			t.Errorf("expected observation 1's drawing without a form, got %q", body)
+		}
+	})
+}
+
+// invalidGeolocations stands in for an upstream that answers every lookup
+// with a response that fails validation.
+type invalidGeolocations struct{}
+
+func (invalidGeolocations) ForIP(ctx context.Context, ip string) (data.Geolocation, error) {
+	return data.Geolocation{}, &fetch.ValidationError{
+		Host:     "ip-api.com",
+		Problems: map[string]string{"timezone": `"Mars/Olympus" isn't an IANA timezone`},
+	}
+}
+
+func TestHandleIndexGet(t *testing.T) {
+	observations := &fakeObservations{byID: map[int64]data.Observation{
+		1: {ID: 1, Timezone: "UTC"},
+	}}
+	drawings := &fakeDrawings{byObservation: map[int64]data.ObservationDrawing{}}
+
+	handler := handleIndexGet(newTestTemplates(t), cookie.NewSigner([]byte("test")), nil, nil, observations, drawings, noForecasts{})
+
+	t.Run("doesn't remember a location from the query string", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/?lat=52.52&lon=13.42", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+		}
+		if cookies := w.Result().Cookies(); len(cookies) > 0 {
+			t.Errorf("expected no cookies, got %v", cookies)
+		}
+	})
+
+	t.Run("blames upstreams for their invalid responses", func(t *testing.T) {
+		clientIPs, err := clientip.NewResolver(nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		handler := handleIndexGet(newTestTemplates(t), cookie.NewSigner([]byte("test")), clientIPs, invalidGeolocations{}, observations, drawings, noForecasts{})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if w.Code != http.StatusBadGateway || strings.Contains(w.Body.String(), "Mars") {
+			t.Errorf("expected a 502 without the upstream's problems, got %d %q", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestRequireSameOrigin(t *testing.T) {
+	handler := requireSameOrigin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for name, tc := range map[string]struct {
+		headers  map[string]string
+		expected int
+	}{
+		"same site fetch":      {map[string]string{"Sec-Fetch-Site": "same-origin"}, http.StatusNoContent},
+		"typed in by the user": {map[string]string{"Sec-Fetch-Site": "none"}, http.StatusNoContent},
+		"cross site fetch":     {map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		"same site subdomain":  {map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+		"matching origin":      {map[string]string{"Origin": "http://example.com"}, http.StatusNoContent},
+		"other origin":         {map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		"null origin":          {map[string]string{"Origin": "null"}, http.StatusForbidden},
+		"no browser headers":   {map[string]string{}, http.StatusNoContent},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://example.com/location", nil)
+			for key, value := range tc.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"weather/internal/fetch"
+
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// writeMetric writes one metric in the Prometheus text format, with a
+// sample per upstream host.
+func writeMetric(w io.Writer, name string, kind string, help string, hosts []fetch.HostStatus, value func(fetch.HostStatus) uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	for _, host := range hosts {
+		fmt.Fprintf(w, "%s{host=%q} %d\n", name, host.Host, value(host))
+	}
+}
+
+func handleMetricsGet(breakers *fetch.Breakers) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts := breakers.Snapshot()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		writeMetric(w, "upstream_circuit_state", "gauge",
+			"Circuit breaker state per upstream host: 0 closed, 1 half-open, 2 open.",
+			hosts, func(h fetch.HostStatus) uint64 { return uint64(h.State) },
+		)
+		writeMetric(w, "upstream_requests_total", "counter",
+			"Requests sent to each upstream host, including retries.",
+			hosts, func(h fetch.HostStatus) uint64 { return h.Requests },
+		)
+		writeMetric(w, "upstream_failures_total", "counter",
+			"Requests to each upstream host that errored, were rate limited or got a 5xx.",
+			hosts, func(h fetch.HostStatus) uint64 { return h.Failures },
+		)
+		writeMetric(w, "upstream_retries_total", "counter",
+			"Requests to each upstream host that were retried.",
+			hosts, func(h fetch.HostStatus) uint64 { return h.Retries },
+		)
+		writeMetric(w, "upstream_rejected_total", "counter",
+			"Requests to each upstream host rejected by its open circuit breaker.",
+			hosts, func(h fetch.HostStatus) uint64 { return h.Rejected },
+		)
+	})
+}
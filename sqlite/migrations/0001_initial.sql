@@ -1,3 +1,6 @@
+-- Tables may already exist in databases created before migrations were
+-- introduced, hence IF NOT EXISTS.
+
 CREATE TABLE IF NOT EXISTS geolocations (
     ip TEXT PRIMARY KEY,
     latitude REAL NOT NULL,
@@ -0,0 +1,9 @@
+-- Tables may already exist in databases created before migrations were
+-- introduced, hence IF NOT EXISTS.
+
+CREATE TABLE IF NOT EXISTS observation_drawing_images (
+    observation_id INTEGER PRIMARY KEY,
+    png BLOB NOT NULL,
+    time_rendered DATETIME NOT NULL,
+    FOREIGN KEY(observation_id) REFERENCES observation_drawings(observation_id)
+);
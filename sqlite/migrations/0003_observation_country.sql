@@ -0,0 +1,6 @@
+-- Observations record the country they were made in so the gallery can be
+-- filtered by it. Earlier observations don't know theirs.
+
+ALTER TABLE observations ADD COLUMN country TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX observations_country ON observations (country);
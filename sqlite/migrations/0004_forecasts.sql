@@ -0,0 +1,35 @@
+-- Forecasts are snapshots of what was expected at a geolocation's
+-- coordinates when they were fetched. Each has a row per hour and per day.
+
+CREATE TABLE forecasts (
+    id INTEGER PRIMARY KEY,
+    latitude REAL NOT NULL,
+    longitude REAL NOT NULL,
+    timezone TEXT NOT NULL,
+    time_generated DATETIME NOT NULL
+);
+
+CREATE INDEX forecasts_location ON forecasts (latitude, longitude, time_generated);
+
+CREATE TABLE forecast_hours (
+    forecast_id INTEGER NOT NULL,
+    time_utc DATETIME NOT NULL,
+    temp_c REAL NOT NULL,
+    precipitation_probability INTEGER NOT NULL,
+    weather_code INTEGER NOT NULL,
+    PRIMARY KEY (forecast_id, time_utc),
+    FOREIGN KEY(forecast_id) REFERENCES forecasts(id)
+);
+
+CREATE TABLE forecast_days (
+    forecast_id INTEGER NOT NULL,
+    date TEXT NOT NULL,
+    temp_min_c REAL NOT NULL,
+    temp_max_c REAL NOT NULL,
+    precipitation_probability_max INTEGER NOT NULL,
+    weather_code INTEGER NOT NULL,
+    sunrise_utc DATETIME NOT NULL,
+    sunset_utc DATETIME NOT NULL,
+    PRIMARY KEY (forecast_id, date),
+    FOREIGN KEY(forecast_id) REFERENCES forecasts(id)
+);
@@ -0,0 +1,13 @@
+-- Observations record more of the current conditions, so visitors can draw
+-- the wind and the clouds too. Earlier observations, and providers that
+-- don't report a condition, leave it NULL.
+
+ALTER TABLE observations ADD COLUMN apparent_temp_c REAL;
+ALTER TABLE observations ADD COLUMN precipitation REAL;
+ALTER TABLE observations ADD COLUMN wind_speed_kmh REAL;
+ALTER TABLE observations ADD COLUMN wind_direction REAL;
+ALTER TABLE observations ADD COLUMN wind_gusts_kmh REAL;
+ALTER TABLE observations ADD COLUMN surface_pressure_hpa REAL;
+ALTER TABLE observations ADD COLUMN cloud_cover REAL;
+ALTER TABLE observations ADD COLUMN uv_index REAL;
+ALTER TABLE observations ADD COLUMN is_day BOOLEAN;
@@ -0,0 +1,19 @@
+-- Observations are shared by every visitor in the same grid cell, a
+-- hundredth of a degree square since that is all Open-Meteo resolves,
+-- during the same time bucket. The unique index lets concurrent requests
+-- upsert rather than store the same weather twice.
+--
+-- Earlier observations are given their own time as their bucket, which no
+-- later observation will share.
+
+ALTER TABLE observations ADD COLUMN cell_lat INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE observations ADD COLUMN cell_lon INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE observations ADD COLUMN bucket_utc DATETIME NOT NULL DEFAULT '';
+
+UPDATE observations
+SET
+    cell_lat = CAST(ROUND(latitude * 100) AS INTEGER),
+    cell_lon = CAST(ROUND(longitude * 100) AS INTEGER),
+    bucket_utc = time_utc;
+
+CREATE UNIQUE INDEX observations_cell_bucket ON observations (cell_lat, cell_lon, bucket_utc);
@@ -0,0 +1,21 @@
+-- Observations record the category of their weather code, so the gallery
+-- can filter and count by category without converting every row's code,
+-- which is stored as text. Codes outside every category are left blank.
+
+ALTER TABLE observations ADD COLUMN weather_category TEXT NOT NULL DEFAULT '';
+
+UPDATE observations
+SET
+    weather_category = CASE
+        WHEN CAST(weather_code AS INTEGER) BETWEEN 0 AND 1 THEN 'clear'
+        WHEN CAST(weather_code AS INTEGER) BETWEEN 2 AND 3 THEN 'cloudy'
+        WHEN CAST(weather_code AS INTEGER) BETWEEN 45 AND 48 THEN 'fog'
+        WHEN CAST(weather_code AS INTEGER) BETWEEN 51 AND 57 THEN 'drizzle'
+        WHEN CAST(weather_code AS INTEGER) BETWEEN 61 AND 67 THEN 'rain'
+        WHEN CAST(weather_code AS INTEGER) BETWEEN 71 AND 77 THEN 'snow'
+        WHEN CAST(weather_code AS INTEGER) BETWEEN 80 AND 86 THEN 'showers'
+        WHEN CAST(weather_code AS INTEGER) BETWEEN 95 AND 99 THEN 'thunderstorm'
+        ELSE ''
+    END;
+
+CREATE INDEX observations_weather_category ON observations (weather_category);
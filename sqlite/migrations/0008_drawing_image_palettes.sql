@@ -0,0 +1,15 @@
+-- Rendered drawings depend on the palette they were rendered with, which
+-- can be reconfigured, so each is stored under a hash of its palette. The
+-- table only caches what can be rendered again, so earlier images are
+-- dropped rather than guessing which palette they were rendered with.
+
+DROP TABLE observation_drawing_images;
+
+CREATE TABLE observation_drawing_images (
+    observation_id INTEGER NOT NULL,
+    palette TEXT NOT NULL,
+    png BLOB NOT NULL,
+    time_rendered DATETIME NOT NULL,
+    PRIMARY KEY (observation_id, palette),
+    FOREIGN KEY(observation_id) REFERENCES observation_drawings(observation_id)
+);
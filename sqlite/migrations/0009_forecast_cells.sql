@@ -0,0 +1,58 @@
+-- Forecasts are shared by every visitor in the same grid cell, like
+-- observations, and only the newest snapshot of each cell is kept. Hours
+-- and days are rebuilt to be deleted along with their snapshot.
+
+ALTER TABLE forecasts ADD COLUMN cell_lat INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE forecasts ADD COLUMN cell_lon INTEGER NOT NULL DEFAULT 0;
+
+UPDATE forecasts
+SET
+    cell_lat = CAST(ROUND(latitude * 100) AS INTEGER),
+    cell_lon = CAST(ROUND(longitude * 100) AS INTEGER);
+
+CREATE TABLE forecast_hours_new (
+    forecast_id INTEGER NOT NULL,
+    time_utc DATETIME NOT NULL,
+    temp_c REAL NOT NULL,
+    precipitation_probability INTEGER NOT NULL,
+    weather_code INTEGER NOT NULL,
+    PRIMARY KEY (forecast_id, time_utc),
+    FOREIGN KEY(forecast_id) REFERENCES forecasts(id) ON DELETE CASCADE
+);
+
+INSERT INTO forecast_hours_new SELECT * FROM forecast_hours;
+DROP TABLE forecast_hours;
+ALTER TABLE forecast_hours_new RENAME TO forecast_hours;
+
+CREATE TABLE forecast_days_new (
+    forecast_id INTEGER NOT NULL,
+    date TEXT NOT NULL,
+    temp_min_c REAL NOT NULL,
+    temp_max_c REAL NOT NULL,
+    precipitation_probability_max INTEGER NOT NULL,
+    weather_code INTEGER NOT NULL,
+    sunrise_utc DATETIME NOT NULL,
+    sunset_utc DATETIME NOT NULL,
+    PRIMARY KEY (forecast_id, date),
+    FOREIGN KEY(forecast_id) REFERENCES forecasts(id) ON DELETE CASCADE
+);
+
+INSERT INTO forecast_days_new SELECT * FROM forecast_days;
+DROP TABLE forecast_days;
+ALTER TABLE forecast_days_new RENAME TO forecast_days;
+
+DELETE FROM forecasts
+WHERE
+    EXISTS (
+        SELECT
+            1
+        FROM
+            forecasts newer
+        WHERE
+            newer.cell_lat = forecasts.cell_lat
+            AND newer.cell_lon = forecasts.cell_lon
+            AND newer.id > forecasts.id
+    );
+
+DROP INDEX forecasts_location;
+CREATE INDEX forecasts_cell ON forecasts (cell_lat, cell_lon, time_generated);
@@ -0,0 +1,5 @@
+-- Geolocations are looked up again once they are older than the location
+-- TTL, since IP addresses get reassigned. Rows stored before now don't say
+-- when they were fetched, so they are treated as long stale.
+
+ALTER TABLE geolocations ADD COLUMN time_fetched DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
@@ -1,8 +1,24 @@
--- name: AddGeolocation :one
+-- name: UpsertGeolocation :one
 INSERT INTO
-    geolocations (ip, latitude, longitude, city, country, timezone)
+    geolocations (
+        ip,
+        latitude,
+        longitude,
+        city,
+        country,
+        timezone,
+        time_fetched
+    )
 VALUES
-    (?, ?, ?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (ip) DO UPDATE
+SET
+    latitude = excluded.latitude,
+    longitude = excluded.longitude,
+    city = excluded.city,
+    country = excluded.country,
+    timezone = excluded.timezone,
+    time_fetched = excluded.time_fetched
 RETURNING
     *;
 
@@ -14,7 +30,7 @@ FROM
 WHERE
     ip = ?;
 
--- name: AddObservation :one
+-- name: UpsertObservation :one
 INSERT INTO
     observations (
         latitude,
@@ -27,10 +43,29 @@ INSERT INTO
         snowfall,
         weather_code,
         time_utc,
-        time_local
+        time_local,
+        country,
+        apparent_temp_c,
+        precipitation,
+        wind_speed_kmh,
+        wind_direction,
+        wind_gusts_kmh,
+        surface_pressure_hpa,
+        cloud_cover,
+        uv_index,
+        is_day,
+        cell_lat,
+        cell_lon,
+        bucket_utc,
+        weather_category
     )
 VALUES
-    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+-- Another request may have stored the cell's observation first. The no-op
+-- update lets RETURNING give back that row rather than nothing.
+ON CONFLICT (cell_lat, cell_lon, bucket_utc) DO UPDATE
+SET
+    cell_lat = excluded.cell_lat
 RETURNING
     *;
 
@@ -42,6 +77,28 @@ FROM
 WHERE
     id = ?;
 
+-- name: GetObservationInCell :one
+SELECT
+    *
+FROM
+    observations
+WHERE
+    cell_lat = ?
+    AND cell_lon = ?
+    AND bucket_utc = ?;
+
+-- name: HasObservationInCell :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            observations
+        WHERE
+            cell_lat = ?
+            AND cell_lon = ?
+    ) AS observed;
+
 -- name: AddObservationDrawing :exec
 INSERT INTO
     observation_drawings (observation_id, data, size_bytes, time_submitted)
@@ -50,10 +107,195 @@ VALUES
 RETURNING
     *;
 
--- todo - returns best
--- name: PriorObservation :one
+-- name: GetObservationDrawing :one
 SELECT
     *
+FROM
+    observation_drawings
+WHERE
+    observation_id = ?;
+
+-- name: GetObservationDrawingImage :one
+SELECT
+    *
+FROM
+    observation_drawing_images
+WHERE
+    observation_id = ?
+    AND palette = ?;
+
+-- name: AddObservationDrawingImage :exec
+INSERT OR REPLACE INTO
+    observation_drawing_images (observation_id, palette, png, time_rendered)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: DeleteStaleObservationDrawingImages :exec
+DELETE FROM
+    observation_drawing_images
+WHERE
+    observation_id = ?
+    AND palette != ?;
+
+-- name: PriorObservationCandidates :many
+SELECT
+    sqlc.embed(o),
+    sqlc.embed(od)
 FROM
     observations o
-    INNER JOIN observation_drawings od ON o.id = od.observation_id;
+    INNER JOIN observation_drawings od ON o.id = od.observation_id
+WHERE
+    o.id != ?
+ORDER BY
+    o.time_utc DESC
+LIMIT
+    ?;
+
+-- name: ListObservations :many
+SELECT
+    sqlc.embed(o),
+    CAST(od.observation_id IS NOT NULL AS BOOLEAN) AS has_drawing
+FROM
+    observations o
+    LEFT JOIN observation_drawings od ON o.id = od.observation_id
+WHERE
+    (sqlc.narg('before_id') IS NULL OR o.id < sqlc.narg('before_id'))
+    AND (sqlc.narg('weather_code') IS NULL OR o.weather_code = sqlc.narg('weather_code'))
+    AND (sqlc.narg('min_temp_c') IS NULL OR o.temp_c >= sqlc.narg('min_temp_c'))
+    AND (sqlc.narg('max_temp_c') IS NULL OR o.temp_c <= sqlc.narg('max_temp_c'))
+    AND (sqlc.narg('since') IS NULL OR o.time_utc >= sqlc.narg('since'))
+    AND (sqlc.narg('until') IS NULL OR o.time_utc < sqlc.narg('until'))
+    AND (
+        CAST(sqlc.narg('has_drawing') AS BOOLEAN) IS NULL
+        OR (od.observation_id IS NOT NULL) = CAST(sqlc.narg('has_drawing') AS BOOLEAN)
+    )
+ORDER BY
+    o.id DESC
+LIMIT
+    sqlc.arg('limit');
+
+-- name: ListDrawnObservations :many
+SELECT
+    sqlc.embed(o),
+    sqlc.embed(od)
+FROM
+    observations o
+    INNER JOIN observation_drawings od ON o.id = od.observation_id
+WHERE
+    (sqlc.narg('before_id') IS NULL OR o.id < sqlc.narg('before_id'))
+    AND (sqlc.narg('weather_category') IS NULL OR o.weather_category = sqlc.narg('weather_category'))
+    AND (sqlc.narg('min_temp_c') IS NULL OR o.temp_c >= sqlc.narg('min_temp_c'))
+    AND (sqlc.narg('max_temp_c') IS NULL OR o.temp_c <= sqlc.narg('max_temp_c'))
+    AND (sqlc.narg('country') IS NULL OR o.country = sqlc.narg('country') COLLATE NOCASE)
+    AND (sqlc.narg('since') IS NULL OR o.time_utc >= sqlc.narg('since'))
+    AND (sqlc.narg('until') IS NULL OR o.time_utc < sqlc.narg('until'))
+ORDER BY
+    o.id DESC
+LIMIT
+    sqlc.arg('limit');
+
+-- name: CountDrawnObservationsByCategory :many
+SELECT
+    o.weather_category,
+    COUNT(*) AS observations
+FROM
+    observations o
+    INNER JOIN observation_drawings od ON o.id = od.observation_id
+WHERE
+    o.weather_category != ''
+GROUP BY
+    o.weather_category;
+
+-- name: ListObservationCountries :many
+SELECT DISTINCT
+    country
+FROM
+    observations
+WHERE
+    country != ''
+ORDER BY
+    country;
+
+-- name: AddForecast :one
+INSERT INTO
+    forecasts (
+        latitude,
+        longitude,
+        cell_lat,
+        cell_lon,
+        timezone,
+        time_generated
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?)
+RETURNING
+    *;
+
+-- name: AddForecastHour :exec
+INSERT INTO
+    forecast_hours (
+        forecast_id,
+        time_utc,
+        temp_c,
+        precipitation_probability,
+        weather_code
+    )
+VALUES
+    (?, ?, ?, ?, ?);
+
+-- name: AddForecastDay :exec
+INSERT INTO
+    forecast_days (
+        forecast_id,
+        date,
+        temp_min_c,
+        temp_max_c,
+        precipitation_probability_max,
+        weather_code,
+        sunrise_utc,
+        sunset_utc
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetRecentForecast :one
+SELECT
+    *
+FROM
+    forecasts
+WHERE
+    cell_lat = ?
+    AND cell_lon = ?
+    AND time_generated >= ?
+ORDER BY
+    time_generated DESC
+LIMIT
+    1;
+
+-- name: DeleteSupersededForecasts :exec
+DELETE FROM
+    forecasts
+WHERE
+    cell_lat = ?
+    AND cell_lon = ?
+    AND id != ?;
+
+-- name: ListForecastHours :many
+SELECT
+    *
+FROM
+    forecast_hours
+WHERE
+    forecast_id = ?
+ORDER BY
+    time_utc;
+
+-- name: ListForecastDays :many
+SELECT
+    *
+FROM
+    forecast_days
+WHERE
+    forecast_id = ?
+ORDER BY
+    date;
@@ -1,4 +1,23 @@
+// Must match drawing.Version in internal/drawing/drawing.go.
+const DRAWING_FORMAT_VERSION = 1;
+// Must match drawing.MaxStrokePoints in internal/drawing/drawing.go.
+const DRAWING_MAX_STROKE_POINTS = 4096;
+
 initObservationCanvas();
+initValidationProblems();
+
+// Validation problems come back as 422s, and other problems with a request
+// as 4xx responses retargeted at the form's problems, neither of which htmx
+// swaps by default.
+function initValidationProblems() {
+    document.addEventListener("htmx:beforeSwap", (ev) => {
+        const xhr = ev.detail.xhr;
+        if (xhr.status === 422 || (xhr.status >= 400 && xhr.getResponseHeader("HX-Retarget"))) {
+            ev.detail.shouldSwap = true;
+            ev.detail.isError = false;
+        }
+    });
+}
 
 function initObservationCanvas() {
     const customElementRegistry = window.customElements;
@@ -8,7 +27,7 @@ function initObservationCanvas() {
     }
 
     class ObservationCanvas extends HTMLElement {
-        static observedAttributes = ["width", "height"];
+        static observedAttributes = ["width", "height", "input"];
 
         pallete;
 
@@ -24,12 +43,18 @@ function initObservationCanvas() {
 
             this.brush = new ObservationCanvasBrush(this, 10);
 
-            this.dataBuffer = new Uint8Array();
+            this.strokes = [];
+            this.inputId = null;
         }
 
         connectedCallback() {
             this.canvas.addEventListener("mousedown", () => {
                 this.brush.start();
+                this.strokes.push({
+                    color: this.pallete.color,
+                    size: this.pallete.size,
+                    points: [],
+                });
             });
 
             this.canvas.addEventListener("mousemove", (ev) => {
@@ -37,7 +62,7 @@ function initObservationCanvas() {
                     const x = ev.clientX - this.offsetLeft;
                     const y = ev.clientY - this.offsetTop;
                     this.brush.paint(x, y);
-                    this.#addData(x, y, this.pallete.color, this.pallete.size);
+                    this.#addData(x, y);
                 }
             });
 
@@ -70,18 +95,73 @@ function initObservationCanvas() {
                     this.canvas.width = this.width;
                     this.#resetData();
                     break;
+                case "input":
+                    this.inputId = newval;
+                    break;
             }
         }
 
         #resetData() {
-            this.dataBuffer = new Uint8Array(this.height * this.width);
+            this.strokes = [];
         }
 
-        #addData(x, y, iColor, iSize) {
-            this.dataBuffer.set([iColor % 16, iSize % 16], x + y * this.width);
+        #addData(x, y) {
+            let stroke = this.strokes[this.strokes.length - 1];
+            // Carry on long strokes in a new one from where the full one
+            // ends, so they stay unbroken.
+            if (stroke.points.length >= DRAWING_MAX_STROKE_POINTS) {
+                stroke = {
+                    color: stroke.color,
+                    size: stroke.size,
+                    points: [stroke.points[stroke.points.length - 1]],
+                };
+                this.strokes.push(stroke);
+            }
+            stroke.points.push([
+                Math.min(Math.max(Math.round(x), 0), this.width - 1),
+                Math.min(Math.max(Math.round(y), 0), this.height - 1),
+            ]);
         }
 
-        #serializeData() {}
+        // Encodes the strokes in the format described in
+        // internal/drawing/drawing.go and base64 encodes them for submission.
+        #serializeData() {
+            const input = document.getElementById(this.inputId);
+            if (!input) {
+                return;
+            }
+
+            let length = 5;
+            for (const stroke of this.strokes) {
+                length += 4 + stroke.points.length * 4;
+            }
+
+            const view = new DataView(new ArrayBuffer(length));
+            view.setUint8(0, DRAWING_FORMAT_VERSION);
+            view.setUint16(1, this.width);
+            view.setUint16(3, this.height);
+
+            let offset = 5;
+            for (const stroke of this.strokes) {
+                view.setUint8(offset, stroke.color);
+                view.setUint8(offset + 1, stroke.size);
+                view.setUint16(offset + 2, stroke.points.length);
+                offset += 4;
+
+                for (const [x, y] of stroke.points) {
+                    view.setUint16(offset, x);
+                    view.setUint16(offset + 2, y);
+                    offset += 4;
+                }
+            }
+
+            let binary = "";
+            for (const byte of new Uint8Array(view.buffer)) {
+                binary += String.fromCharCode(byte);
+            }
+
+            input.value = btoa(binary);
+        }
     }
 
     class ObservationCanvasBrush {
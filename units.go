@@ -0,0 +1,89 @@
+package main
+
+import (
+	"weather/internal/cookie"
+	"weather/internal/templates"
+	"weather/internal/validation"
+	"weather/internal/weather"
+
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	unitsCookieName   = "units"
+	unitsCookieMaxAge = 365 * 24 * time.Hour
+)
+
+// unitsPreference is the unit system the visitor chose, which takes
+// precedence over the one their locale suggests.
+type unitsPreference struct {
+	System weather.System `json:"system"`
+}
+
+// resolveUnits finds how to show values to the visitor: in the unit system
+// they chose, or else the one most used where their browser's language is
+// from.
+func resolveUnits(r *http.Request, signer *cookie.Signer) weather.Units {
+	units := weather.Units{Locale: weather.ParseLocale(r.Header.Get("Accept-Language"))}
+	units.System = units.Locale.DefaultSystem()
+
+	preference := unitsPreference{}
+	switch err := signer.Get(r, unitsCookieName, &preference); {
+	case err == nil:
+		if system, err := weather.ParseSystem(string(preference.System)); err == nil {
+			units.System = system
+		}
+	case errors.Is(err, cookie.ErrInvalid):
+		break
+	default:
+		log.Printf("error reading units preference: %v", err)
+	}
+
+	return units
+}
+
+// redirectBack sends the visitor back to the page they came from, or the
+// index when that isn't one of ours. Like redirectHome, htmx is asked to
+// navigate rather than follow the redirect.
+func redirectBack(w http.ResponseWriter, r *http.Request) {
+	back := "/"
+	if referer, err := url.Parse(r.Referer()); err == nil && referer.Host == r.Host && strings.HasPrefix(referer.Path, "/") {
+		back = referer.RequestURI()
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", back)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+func handleUnitsPost(tmpl *templates.TemplateEngine, signer *cookie.Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "uh oh, I couldn't read those units :(", http.StatusBadRequest)
+			return
+		}
+
+		system, err := weather.ParseSystem(r.PostForm.Get("system"))
+		if err != nil {
+			validation.WriteError(w, r, validation.Problem("system", "must be metric or imperial"), "system", tmpl, "find .validation-problems")
+			return
+		}
+
+		if err := signer.Set(w, unitsCookieName, unitsPreference{System: system}, unitsCookieMaxAge); err != nil {
+			log.Printf("error storing units preference: %v", err)
+			http.Error(w, "uh oh, I beefed it :(", http.StatusInternalServerError)
+			return
+		}
+
+		redirectBack(w, r)
+	})
+}